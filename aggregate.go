@@ -0,0 +1,208 @@
+package crudo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const PathAggregate = "aggregate"
+
+// 支持的聚合函数
+var aggregateFuncs = map[string]string{
+	"count": "COUNT",
+	"sum":   "SUM",
+	"avg":   "AVG",
+	"min":   "MIN",
+	"max":   "MAX",
+}
+
+// 支持的时间截断单位
+var timeBucketUnits = map[string]bool{
+	"hour":  true,
+	"day":   true,
+	"week":  true,
+	"month": true,
+}
+
+// AggregateMetric 单个聚合指标，Field 为空时仅允许 count
+type AggregateMetric struct {
+	Func  string `json:"func"`
+	Field string `json:"field"`
+}
+
+// TimeBucket 按时间截断分组
+type TimeBucket struct {
+	Field string `json:"field"`
+	Unit  string `json:"unit"`
+}
+
+// AggregateRequest 聚合查询请求
+// 查询参数示例：groupBy=status&metrics=count,sum:amount&bucket=created_at:day&status_in=paid,shipped
+type AggregateRequest struct {
+	QueryParams
+	GroupBy []string          `json:"groupBy"`
+	Metrics []AggregateMetric `json:"metrics"`
+	Bucket  *TimeBucket       `json:"bucket"`
+}
+
+func (c *Crud) requestToAggregate() ParseRequestFunc {
+	parseQuery := c.queryParamsParser(false)
+	return func(ctx *fiber.Ctx) (any, error) {
+		params, err := parseQuery(ctx)
+		if err != nil {
+			return nil, err
+		}
+		req := AggregateRequest{QueryParams: params.(QueryParams)}
+
+		if groupBy := ctx.Query("groupBy"); groupBy != "" {
+			for _, field := range strings.Split(groupBy, ",") {
+				req.GroupBy = append(req.GroupBy, c.dbFieldName(field))
+			}
+		}
+
+		metrics := ctx.Query("metrics", "count")
+		for _, metric := range strings.Split(metrics, ",") {
+			fn, field, _ := strings.Cut(metric, ":")
+			if field != "" {
+				field = c.dbFieldName(field)
+			}
+			req.Metrics = append(req.Metrics, AggregateMetric{Func: fn, Field: field})
+		}
+
+		if bucket := ctx.Query("bucket"); bucket != "" {
+			field, unit, ok := strings.Cut(bucket, ":")
+			if !ok {
+				return nil, fmt.Errorf("invalid request body: bucket must be field:unit, got %s", bucket)
+			}
+			req.Bucket = &TimeBucket{Field: c.dbFieldName(field), Unit: unit}
+		}
+		return req, nil
+	}
+}
+
+// aggregateOperation 按分组列和时间截断计算聚合指标，所有列都会与缓存的列信息校验
+func (c *Crud) aggregateOperation() DataOperationFunc {
+	return func(input any) (any, error) {
+		req, ok := input.(AggregateRequest)
+		if !ok {
+			return nil, errors.New("invalid data format")
+		}
+		if len(req.Metrics) == 0 {
+			return nil, errors.New("invalid request body: metrics cannot be empty")
+		}
+
+		columns := c.queryBuilder.columnCache
+		var selects, groups []string
+
+		for _, field := range req.GroupBy {
+			if req.Bucket != nil && field == req.Bucket.Field {
+				continue
+			}
+			if _, ok := columns[field]; !ok {
+				return nil, fmt.Errorf("invalid request body: unknown group field %s", field)
+			}
+			selects = append(selects, c.Dialect.Quote(field))
+			groups = append(groups, c.Dialect.Quote(field))
+		}
+
+		if req.Bucket != nil {
+			column, ok := columns[req.Bucket.Field]
+			if !ok {
+				return nil, fmt.Errorf("invalid request body: unknown bucket field %s", req.Bucket.Field)
+			}
+			if !isTimeField(column.DataType) {
+				return nil, fmt.Errorf("invalid request body: bucket field %s is not a time column", req.Bucket.Field)
+			}
+			if !timeBucketUnits[req.Bucket.Unit] {
+				return nil, fmt.Errorf("invalid request body: unsupported bucket unit %s", req.Bucket.Unit)
+			}
+			expr := c.Dialect.DateTrunc(req.Bucket.Unit, c.Dialect.Quote(req.Bucket.Field))
+			selects = append(selects, fmt.Sprintf("%s AS %s", expr, c.Dialect.Quote(req.Bucket.Field)))
+			groups = append(groups, expr)
+		}
+
+		for _, metric := range req.Metrics {
+			fn, ok := aggregateFuncs[metric.Func]
+			if !ok {
+				return nil, fmt.Errorf("invalid request body: unsupported aggregate function %s", metric.Func)
+			}
+			if metric.Field == "" {
+				if metric.Func != "count" {
+					return nil, fmt.Errorf("invalid request body: %s requires a field", metric.Func)
+				}
+				selects = append(selects, fmt.Sprintf("COUNT(*) AS %s", c.Dialect.Quote("count")))
+				continue
+			}
+			if err := c.checkAggregateField(metric); err != nil {
+				return nil, err
+			}
+			alias := metric.Func + "_" + c.apiFieldName(metric.Field)
+			selects = append(selects, fmt.Sprintf("%s(%s) AS %s", fn, c.Dialect.Quote(metric.Field), c.Dialect.Quote(alias)))
+		}
+
+		where, values, err := c.whereClause(req.QueryParams, 1)
+		if err != nil {
+			return nil, err
+		}
+
+		query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(selects, ", "), c.Dialect.Quote(c.Table))
+		if where != "" {
+			query += " WHERE " + where
+		}
+		if len(groups) > 0 {
+			query += " GROUP BY " + strings.Join(groups, ", ") + " ORDER BY " + strings.Join(groups, ", ")
+		}
+
+		rows, err := queryRows(c.Db.Chain(), query, values...)
+		if err != nil {
+			return nil, fmt.Errorf("aggregate failed: %w", err)
+		}
+
+		result := make([]map[string]any, len(rows))
+		for i, row := range rows {
+			if result[i], err = c.transferData(row, true); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	}
+}
+
+// checkAggregateField 检查聚合列是否存在、在白名单内，且 count 以外的函数只能用于数值列
+func (c *Crud) checkAggregateField(metric AggregateMetric) error {
+	column, ok := c.queryBuilder.columnCache[metric.Field]
+	if !ok {
+		return fmt.Errorf("invalid request body: unknown aggregate field %s", metric.Field)
+	}
+	if len(c.AggregateFields) > 0 && !contains(c.AggregateFields, metric.Field) {
+		return fmt.Errorf("invalid request body: field %s is not allowed for aggregation", metric.Field)
+	}
+	if metric.Func != "count" && !isNumericType(column.DataType) {
+		return fmt.Errorf("invalid request body: field %s is not a numeric column", metric.Field)
+	}
+	return nil
+}
+
+// isNumericType 判断字段类型是否为数值类型
+func isNumericType(dataType string) bool {
+	if isIntegerType(dataType) {
+		return true
+	}
+	switch strings.ToLower(dataType) {
+	case "float32", "float64", "decimal", "numeric", "real", "double":
+		return true
+	}
+	return false
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
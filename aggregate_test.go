@@ -0,0 +1,33 @@
+package crudo
+
+import (
+	"testing"
+
+	"github.com/kmlixh/gom/v4/define"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckAggregateField(t *testing.T) {
+	c := &Crud{
+		Dialect: PostgresDialect,
+		queryBuilder: &QueryBuilder{columnCache: map[string]define.ColumnInfo{
+			"amount": {Name: "amount", DataType: "float64"},
+			"qty":    {Name: "qty", DataType: "int32"},
+			"name":   {Name: "name", DataType: "string"},
+		}},
+	}
+
+	assert.NoError(t, c.checkAggregateField(AggregateMetric{Func: "sum", Field: "amount"}))
+	assert.NoError(t, c.checkAggregateField(AggregateMetric{Func: "count", Field: "name"}))
+	assert.Error(t, c.checkAggregateField(AggregateMetric{Func: "avg", Field: "name"}))
+	assert.Error(t, c.checkAggregateField(AggregateMetric{Func: "sum", Field: "missing"}))
+
+	c.AggregateFields = []string{"qty"}
+	assert.NoError(t, c.checkAggregateField(AggregateMetric{Func: "max", Field: "qty"}))
+	assert.Error(t, c.checkAggregateField(AggregateMetric{Func: "sum", Field: "amount"}))
+}
+
+func TestDialectDateTrunc(t *testing.T) {
+	assert.Equal(t, `date_trunc('day', "created_at")`, PostgresDialect.DateTrunc("day", `"created_at"`))
+	assert.Equal(t, "DATE_FORMAT(`created_at`, '%Y-%m-01')", MySQLDialect.DateTrunc("month", "`created_at`"))
+}
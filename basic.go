@@ -1,6 +1,7 @@
 package crudo
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 	"time"
@@ -22,6 +23,28 @@ type (
 	ValidationMiddleware func(*fiber.Ctx) error
 )
 
+// ErrVersionConflict 表示更新时提交的版本与数据库中的版本不一致
+var ErrVersionConflict = errors.New("version conflict")
+
+// InvalidParam 描述一个无效的请求参数
+type InvalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// InvalidParamsError 表示请求中存在无效参数，RenderErrs 会以 400 返回全部无效参数
+type InvalidParamsError struct {
+	Params []InvalidParam
+}
+
+func (e *InvalidParamsError) Error() string {
+	parts := make([]string, len(e.Params))
+	for i, p := range e.Params {
+		parts[i] = p.Name + ": " + p.Reason
+	}
+	return "invalid request params: " + strings.Join(parts, "; ")
+}
+
 // RenderJson 渲染JSON响应
 func RenderJson(c *fiber.Ctx, code int, msg string, data interface{}) error {
 	return c.Status(code).JSON(CodeMsg{
@@ -52,18 +75,36 @@ func RenderErrs(c *fiber.Ctx, err error) error {
 
 	// 获取适当的错误代码
 	code := http.StatusInternalServerError
-	if strings.Contains(err.Error(), "invalid request body") ||
+	var data any
+	var paramsErr *InvalidParamsError
+	var fiberErr *fiber.Error
+	if errors.As(err, &paramsErr) {
+		code = http.StatusBadRequest
+		data = paramsErr.Params
+	} else if errors.As(err, &fiberErr) {
+		code = fiberErr.Code
+	} else if strings.Contains(err.Error(), "invalid request body") ||
 		strings.Contains(err.Error(), "ids cannot be empty") {
 		code = http.StatusBadRequest
+	} else if errors.Is(err, ErrVersionConflict) {
+		code = http.StatusConflict
+	} else if errors.Is(err, ErrReadOnly) {
+		code = http.StatusMethodNotAllowed
 	} else if strings.Contains(err.Error(), "not found") {
 		code = http.StatusNotFound
 	}
 
+	// 批量请求失败时返回每一步的执行结果
+	var batchErr *BatchError
+	if errors.As(err, &batchErr) {
+		data = batchErr.Results
+	}
+
 	// 始终返回 HTTP 200 OK，但在响应体中包含错误状态码
 	return c.Status(http.StatusOK).JSON(CodeMsg{
 		Code:    code,
 		Message: err.Error(),
-		Data:    nil,
+		Data:    data,
 	})
 }
 
@@ -0,0 +1,310 @@
+package crudo
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/kmlixh/gom/v4"
+)
+
+// PathBatch CrudManager 的事务批量接口，按顺序在同一个事务中执行多个表的写入
+const PathBatch = "_batch"
+
+// 批量步骤的执行状态
+const (
+	BatchStatusOK         = "ok"          // 执行成功并已提交
+	BatchStatusFailed     = "failed"      // 执行失败，整个批量已回滚
+	BatchStatusRolledBack = "rolled_back" // 执行成功，但因其他步骤失败被回滚
+	BatchStatusSkipped    = "skipped"     // 前面的步骤失败，未执行
+)
+
+// 引用前面步骤结果的值，如 "${order.id}" 或 "${0.id}"，必须是完整的字符串值
+var batchRefPattern = regexp.MustCompile(`^\$\{([A-Za-z0-9_-]+)((?:\.[A-Za-z0-9_-]+)+)\}$`)
+
+// BatchRequest 批量请求
+type BatchRequest struct {
+	Steps []BatchStep `json:"steps"`
+}
+
+// BatchStep 批量请求中的一步
+// Body 与对应单表接口的请求体相同，delete 时为 {"ids": [...]}；其中形如 "${id.field}" 的值会替换为前面步骤结果中的字段
+type BatchStep struct {
+	ID   string         `json:"id,omitempty"` // 步骤名称，供后续步骤引用，也可以使用步骤序号引用
+	Path string         `json:"path"`         // 表配置的 path_prefix
+	Op   string         `json:"op"`           // save、update 或 delete
+	Body map[string]any `json:"body"`
+}
+
+// BatchStepResult 批量请求中单个步骤的执行结果
+type BatchStepResult struct {
+	Index  int    `json:"index"`
+	ID     string `json:"id,omitempty"`
+	Status string `json:"status"`
+	Data   any    `json:"data,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BatchError 批量请求执行失败，RenderErrs 按失败原因返回状态码，并在 data 中返回每一步的结果
+type BatchError struct {
+	Index   int // 失败的步骤序号，提交失败时为 -1
+	Err     error
+	Results []BatchStepResult
+}
+
+func (e *BatchError) Error() string {
+	if e.Index < 0 {
+		return fmt.Sprintf("batch failed: %v", e.Err)
+	}
+	return fmt.Sprintf("batch step %d failed: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// batchOperationFunc 在调用方的事务中执行一个批量步骤，body 为已替换引用的请求体
+type batchOperationFunc func(tx *gom.Chain, body map[string]any) (any, error)
+
+// handleBatch 处理 /_batch 请求
+func (cm *CrudManager) handleBatch(ctx *fiber.Ctx) error {
+	var req BatchRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return RenderErrs(ctx, fmt.Errorf("invalid request body: %w", err))
+	}
+	results, err := cm.runBatch(ctx, req.Steps)
+	if err != nil {
+		return RenderErrs(ctx, err)
+	}
+	return RenderOk(ctx, results)
+}
+
+// batchCruds 校验批量步骤并返回每一步对应的 Crud 与处理器，全部步骤必须位于同一个数据库
+// 通过 AddHandler 替换过的写操作无法在事务中执行，直接拒绝
+func (cm *CrudManager) batchCruds(steps []BatchStep) ([]*Crud, []*RequestHandler, error) {
+	if len(steps) == 0 {
+		return nil, nil, &InvalidParamsError{Params: []InvalidParam{{Name: "steps", Reason: "cannot be empty"}}}
+	}
+
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	cruds := make([]*Crud, len(steps))
+	handlers := make([]*RequestHandler, len(steps))
+	ids := make(map[string]bool)
+	var invalid []InvalidParam
+	for i, step := range steps {
+		name := fmt.Sprintf("steps[%d]", i)
+		if step.ID != "" {
+			if ids[step.ID] {
+				invalid = append(invalid, InvalidParam{Name: name + ".id", Reason: "duplicate step id " + step.ID})
+			}
+			ids[step.ID] = true
+		}
+
+		path := step.Path
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		crud, ok := cm.routes[path].(*Crud)
+		if !ok {
+			invalid = append(invalid, InvalidParam{Name: name + ".path", Reason: "path not configured: " + step.Path})
+			continue
+		}
+		var handler *RequestHandler
+		switch step.Op {
+		case PathSave, PathUpdate, PathDelete:
+			var enabled bool
+			if handler, enabled = crud.GetHandler(step.Op); !enabled || handler == nil {
+				invalid = append(invalid, InvalidParam{Name: name + ".op", Reason: "operation not configured: " + step.Op})
+				continue
+			}
+			if handler.batchOperation == nil {
+				invalid = append(invalid, InvalidParam{Name: name + ".op", Reason: "custom handler cannot run in a batch: " + step.Op})
+				continue
+			}
+		default:
+			invalid = append(invalid, InvalidParam{Name: name + ".op", Reason: "unsupported operation: " + step.Op})
+			continue
+		}
+		if crud.ReadOnly {
+			invalid = append(invalid, InvalidParam{Name: name + ".path", Reason: "table is read-only: " + step.Path})
+			continue
+		}
+		if cruds[0] != nil && crud.Db != cruds[0].Db {
+			invalid = append(invalid, InvalidParam{Name: name + ".path", Reason: "all steps must use the same database"})
+			continue
+		}
+		cruds[i] = crud
+		handlers[i] = handler
+	}
+	if len(invalid) > 0 {
+		return nil, nil, &InvalidParamsError{Params: invalid}
+	}
+	return cruds, handlers, nil
+}
+
+// runBatch 在一个事务中依次执行全部步骤，任一步骤失败时回滚全部写入
+// 各步骤处理器的 PreHandle 在事务开始前按顺序以批量请求的上下文执行，任一拒绝时不执行任何步骤
+func (cm *CrudManager) runBatch(ctx *fiber.Ctx, steps []BatchStep) ([]BatchStepResult, error) {
+	cruds, handlers, err := cm.batchCruds(steps)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]BatchStepResult, len(steps))
+	for i, step := range steps {
+		results[i] = BatchStepResult{Index: i, ID: step.ID, Status: BatchStatusSkipped}
+	}
+
+	for i, handler := range handlers {
+		if handler.PreHandle == nil {
+			continue
+		}
+		if err := handler.PreHandle(ctx); err != nil {
+			results[i].Status = BatchStatusFailed
+			results[i].Error = err.Error()
+			return nil, &BatchError{Index: i, Err: err, Results: results}
+		}
+	}
+
+	failed := -1
+	err = cruds[0].Db.Chain().Transaction(func(tx *gom.Chain) error {
+		for i, step := range steps {
+			data, err := runBatchStep(tx, handlers[i], step, steps[:i], results[:i])
+			if err != nil {
+				failed = i
+				return err
+			}
+			results[i].Status = BatchStatusOK
+			results[i].Data = data
+		}
+		return nil
+	})
+	if err == nil {
+		return results, nil
+	}
+
+	for i := range results {
+		if results[i].Status == BatchStatusOK {
+			results[i].Status = BatchStatusRolledBack
+		}
+	}
+	if failed >= 0 {
+		results[failed].Status = BatchStatusFailed
+		results[failed].Error = err.Error()
+	}
+	return nil, &BatchError{Index: failed, Err: err, Results: results}
+}
+
+// runBatchStep 替换请求体中的引用后，在事务中执行一个步骤
+func runBatchStep(tx *gom.Chain, handler *RequestHandler, step BatchStep, prev []BatchStep, results []BatchStepResult) (any, error) {
+	resolved, err := resolveBatchRefs(step.Body, prev, results)
+	if err != nil {
+		return nil, err
+	}
+	body, _ := resolved.(map[string]any)
+	if body == nil {
+		body = make(map[string]any)
+	}
+	return handler.batchOperation(tx, body)
+}
+
+// batchOperation 返回默认 save、update、delete 操作在批量事务中的执行函数
+func (c *Crud) batchOperation(op string) batchOperationFunc {
+	return func(tx *gom.Chain, body map[string]any) (any, error) {
+		switch op {
+		case PathSave, PathUpdate:
+			data, err := c.transferData(body, false)
+			if err != nil {
+				return nil, err
+			}
+			if op == PathSave {
+				return c.save(tx, data)
+			}
+			return c.update(tx, data)
+		default:
+			ids, ok := body["ids"].([]any)
+			if !ok && body["ids"] != nil {
+				return nil, fmt.Errorf("invalid request body: ids must be an array")
+			}
+			return c.deleteRecords(tx, DeleteRequest{IDs: ids})
+		}
+	}
+}
+
+// resolveBatchRefs 递归替换值中对前面步骤结果的引用，引用的值保留原有类型
+func resolveBatchRefs(value any, prev []BatchStep, results []BatchStepResult) (any, error) {
+	switch v := value.(type) {
+	case map[string]any:
+		resolved := make(map[string]any, len(v))
+		for key, item := range v {
+			r, err := resolveBatchRefs(item, prev, results)
+			if err != nil {
+				return nil, err
+			}
+			resolved[key] = r
+		}
+		return resolved, nil
+	case []any:
+		resolved := make([]any, len(v))
+		for i, item := range v {
+			r, err := resolveBatchRefs(item, prev, results)
+			if err != nil {
+				return nil, err
+			}
+			resolved[i] = r
+		}
+		return resolved, nil
+	case string:
+		match := batchRefPattern.FindStringSubmatch(v)
+		if match == nil {
+			return v, nil
+		}
+		return batchRefValue(match[1], strings.Split(match[2][1:], "."), prev, results)
+	default:
+		return value, nil
+	}
+}
+
+// batchRefValue 按步骤名称或序号查找前面步骤的结果，并沿字段路径取值，路径中的数字可以索引列表
+func batchRefValue(step string, path []string, prev []BatchStep, results []BatchStepResult) (any, error) {
+	index := -1
+	for i, s := range prev {
+		if s.ID != "" && s.ID == step {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		if i, err := strconv.Atoi(step); err == nil && i >= 0 && i < len(prev) {
+			index = i
+		}
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("invalid request body: reference to unknown or later step %s", step)
+	}
+
+	var current any = results[index].Data
+	for _, key := range path {
+		switch v := current.(type) {
+		case map[string]any:
+			item, ok := v[key]
+			if !ok {
+				return nil, fmt.Errorf("invalid request body: step %s has no field %s", step, strings.Join(path, "."))
+			}
+			current = item
+		case []map[string]any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf("invalid request body: step %s has no field %s", step, strings.Join(path, "."))
+			}
+			current = v[i]
+		default:
+			return nil, fmt.Errorf("invalid request body: step %s has no field %s", step, strings.Join(path, "."))
+		}
+	}
+	return current, nil
+}
@@ -0,0 +1,136 @@
+package crudo
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/kmlixh/gom/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// newBatchHandler 返回可以在批量事务中执行的处理器，operation 为空时直接返回请求体
+func newBatchHandler(operation batchOperationFunc) *RequestHandler {
+	if operation == nil {
+		operation = func(tx *gom.Chain, body map[string]any) (any, error) { return body, nil }
+	}
+	return &RequestHandler{batchOperation: operation}
+}
+
+func TestResolveBatchRefs(t *testing.T) {
+	prev := []BatchStep{{ID: "order"}, {}}
+	results := []BatchStepResult{
+		{Index: 0, ID: "order", Data: map[string]any{"id": int64(7), "items": []map[string]any{{"id": int64(70)}}}},
+		{Index: 1, Data: map[string]any{"deleted_count": int64(1)}},
+	}
+
+	body := map[string]any{
+		"order_id": "${order.id}",
+		"count":    "${1.deleted_count}",
+		"note":     "id is ${order.id}",
+		"lines":    []any{map[string]any{"item_id": "${order.items.0.id}"}},
+	}
+	resolved, err := resolveBatchRefs(body, prev, results)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"order_id": int64(7),
+		"count":    int64(1),
+		"note":     "id is ${order.id}",
+		"lines":    []any{map[string]any{"item_id": int64(70)}},
+	}, resolved)
+
+	_, err = resolveBatchRefs(map[string]any{"x": "${customer.id}"}, prev, results)
+	assert.ErrorContains(t, err, "invalid request body")
+	_, err = resolveBatchRefs(map[string]any{"x": "${2.id}"}, prev, results)
+	assert.ErrorContains(t, err, "invalid request body")
+	_, err = resolveBatchRefs(map[string]any{"x": "${order.code}"}, prev, results)
+	assert.ErrorContains(t, err, "invalid request body")
+	_, err = resolveBatchRefs(map[string]any{"x": "${order.items.1.id}"}, prev, results)
+	assert.ErrorContains(t, err, "invalid request body")
+}
+
+func TestBatchCruds(t *testing.T) {
+	db, other := &gom.DB{}, &gom.DB{}
+	orders := &Crud{Table: "orders", Db: db, HandlerMap: map[string]*RequestHandler{PathSave: newBatchHandler(nil), PathUpdate: {}}}
+	items := &Crud{Table: "order_items", Db: db, HandlerMap: map[string]*RequestHandler{PathSave: newBatchHandler(nil), PathDelete: newBatchHandler(nil)}}
+	logs := &Crud{Table: "logs", Db: other, HandlerMap: map[string]*RequestHandler{PathSave: newBatchHandler(nil)}}
+	cm := &CrudManager{routes: map[string]ICrud{"/orders": orders, "/order_items": items, "/logs": logs}}
+
+	cruds, handlers, err := cm.batchCruds([]BatchStep{
+		{ID: "order", Path: "/orders", Op: PathSave},
+		{Path: "order_items", Op: PathDelete},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []*Crud{orders, items}, cruds)
+	assert.Equal(t, []*RequestHandler{orders.HandlerMap[PathSave], items.HandlerMap[PathDelete]}, handlers)
+
+	_, _, err = cm.batchCruds(nil)
+	var paramsErr *InvalidParamsError
+	assert.ErrorAs(t, err, &paramsErr)
+
+	_, _, err = cm.batchCruds([]BatchStep{
+		{ID: "order", Path: "/orders", Op: PathSave},
+		{ID: "order", Path: "/orders", Op: PathDelete},
+		{Path: "/customers", Op: PathSave},
+		{Path: "/order_items", Op: PathGet},
+		{Path: "/logs", Op: PathSave},
+		{Path: "/orders", Op: PathUpdate},
+	})
+	assert.ErrorAs(t, err, &paramsErr)
+	names := make([]string, len(paramsErr.Params))
+	for i, p := range paramsErr.Params {
+		names[i] = p.Name
+	}
+	assert.Equal(t, []string{"steps[1].id", "steps[1].op", "steps[2].path", "steps[3].op", "steps[4].path", "steps[5].op"}, names)
+}
+
+func TestBatchPreHandleRejects(t *testing.T) {
+	var executed []string
+	record := func(name string) batchOperationFunc {
+		return func(tx *gom.Chain, body map[string]any) (any, error) {
+			executed = append(executed, name)
+			return body, nil
+		}
+	}
+	db := &gom.DB{}
+	orders := &Crud{Table: "orders", Db: db, HandlerMap: map[string]*RequestHandler{PathSave: newBatchHandler(record("orders"))}}
+	items := &Crud{Table: "order_items", Db: db, HandlerMap: map[string]*RequestHandler{PathSave: newBatchHandler(record("order_items"))}}
+	items.HandlerMap[PathSave].PreHandle = func(ctx *fiber.Ctx) error {
+		if ctx.Get("X-Role") != "admin" {
+			return fiber.ErrForbidden
+		}
+		return nil
+	}
+	cm := &CrudManager{routes: map[string]ICrud{"/orders": orders, "/order_items": items}}
+
+	app := fiber.New()
+	app.Post("/"+PathBatch, cm.handleBatch)
+	req := httptest.NewRequest(http.MethodPost, "/"+PathBatch, strings.NewReader(
+		`{"steps":[{"id":"order","path":"orders","op":"save","body":{"no":"A1"}},{"path":"order_items","op":"save","body":{"order_id":"${order.id}"}}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+
+	var body struct {
+		Code int               `json:"code"`
+		Data []BatchStepResult `json:"data"`
+	}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, http.StatusForbidden, body.Code)
+	assert.Equal(t, []string{BatchStatusSkipped, BatchStatusFailed}, []string{body.Data[0].Status, body.Data[1].Status})
+	// 被拒绝的批量请求不会执行任何步骤
+	assert.Empty(t, executed)
+}
+
+func TestBatchError(t *testing.T) {
+	err := &BatchError{Index: 1, Err: ErrVersionConflict, Results: []BatchStepResult{
+		{Index: 0, Status: BatchStatusRolledBack},
+		{Index: 1, Status: BatchStatusFailed, Error: ErrVersionConflict.Error()},
+	}}
+	assert.True(t, errors.Is(err, ErrVersionConflict))
+	assert.Equal(t, "batch step 1 failed: version conflict", err.Error())
+}
@@ -1,10 +1,12 @@
 package crudo
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -30,6 +32,28 @@ const (
 	PathList   = "list"
 	PathPage   = "page"
 	PathTable  = "table"
+
+	PathBatchSave   = "batchSave"
+	PathUpsert      = "upsert"
+	PathUpdateWhere = "updateWhere"
+	PathRestore     = "restore"
+	PathCount       = "count"
+)
+
+const (
+	// StrictFiltersWrite 仅对写操作（delete、restore、updateWhere）严格校验查询参数
+	StrictFiltersWrite = "write"
+	// StrictFiltersAll 对全部操作严格校验查询参数
+	StrictFiltersAll = "all"
+	// StrictFiltersNone 忽略无效的查询参数
+	StrictFiltersNone = "none"
+)
+
+const (
+	// BatchModeAtomic 批量写入全部成功或全部回滚
+	BatchModeAtomic = "atomic"
+	// BatchModePartial 批量写入尽量执行，逐行返回错误
+	BatchModePartial = "partial"
 )
 
 type RequestHandler struct {
@@ -39,6 +63,8 @@ type RequestHandler struct {
 	DataOperationFunc
 	TransferResultFunc
 	RenderResponseFunc
+
+	batchOperation batchOperationFunc // 默认写操作在 /_batch 事务中的执行方式，通过 AddHandler 替换的处理器为空
 }
 
 type Column struct {
@@ -68,16 +94,112 @@ type ICrud interface {
 }
 
 type Crud struct {
-	Prefix         string
-	Table          string
-	Db             *gom.DB
-	TransferMap    map[string]string
-	FieldOfList    []string
-	FieldOfDetail  []string
-	HandlerMap     map[string]*RequestHandler // key is now full path: prefix + "/" + operation
-	handlerFilters []string
-	queryBuilder   *QueryBuilder
-	mu             sync.RWMutex
+	Prefix              string
+	Table               string
+	Db                  *gom.DB
+	TransferMap         map[string]string
+	FieldOfList         []string
+	FieldOfDetail       []string
+	HandlerMap          map[string]*RequestHandler // key is now full path: prefix + "/" + operation
+	Dialect             Dialect
+	BatchMode           string
+	ConflictFields      []string             // upsert 判断冲突的列，默认为主键
+	UpsertFields        []string             // upsert 冲突时覆盖的列，默认为请求中除冲突列以外的全部列
+	SoftDeleteField     string               // 软删除标记列（时间或布尔类型），为空时执行物理删除
+	VersionField        string               // 乐观锁版本列（整数或时间类型），为空时不做版本检查
+	AggregateFields     []string             // 允许聚合的数值列，为空时允许全部数值列
+	MaxFieldOfList      []string             // list/page 中客户端通过 fields 参数最多可选择的字段，为空时只能选择 FieldOfList 中的字段
+	MaxFieldOfDetail    []string             // get 中客户端通过 fields 参数最多可选择的字段，为空时只能选择 FieldOfDetail 中的字段
+	StrictFilters       string               // 查询参数严格校验模式：write（默认）、all 或 none
+	SortableFields      []string             // 允许排序的列，为空时允许全部列
+	MaxFilterDepth      int                  // query 操作中过滤条件的最大嵌套深度
+	MaxFilterConditions int                  // query 操作中过滤条件的最大条件数
+	SearchFields        []string             // q 参数搜索的列
+	SearchFullText      bool                 // 是否使用全文搜索，否则对 SearchFields 做包含匹配
+	SearchVector        string               // PostgreSQL 预先计算的 tsvector 列
+	SearchLanguage      string               // PostgreSQL 全文搜索配置
+	Relations           map[string]*Relation // 可通过 expand 参数嵌入的关联，按名称索引
+	ReadOnly            bool                 // 只读表或视图，只注册 ReadOnlyPaths 中的操作
+	IdentityField       string               // 唯一标识记录的列，用于没有主键的视图，设置后 get 必须按该列查询
+	handlerFilters      []string
+	queryBuilder        *QueryBuilder
+	mu                  sync.RWMutex
+}
+
+// CrudOption 用于在创建 Crud 时设置可选配置
+type CrudOption func(*Crud)
+
+// WithBatchMode 设置批量写入模式，可选 BatchModeAtomic（默认）或 BatchModePartial
+func WithBatchMode(mode string) CrudOption {
+	return func(c *Crud) {
+		c.BatchMode = mode
+	}
+}
+
+// WithUpsert 设置 upsert 的冲突列与冲突时覆盖的列，为空时使用默认值
+func WithUpsert(conflictFields, upsertFields []string) CrudOption {
+	return func(c *Crud) {
+		c.ConflictFields = conflictFields
+		c.UpsertFields = upsertFields
+	}
+}
+
+// WithSoftDelete 设置软删除标记列，列类型必须为时间或布尔
+func WithSoftDelete(field string) CrudOption {
+	return func(c *Crud) {
+		c.SoftDeleteField = field
+	}
+}
+
+// WithVersion 设置乐观锁版本列，列类型必须为整数或时间
+func WithVersion(field string) CrudOption {
+	return func(c *Crud) {
+		c.VersionField = field
+	}
+}
+
+// WithAggregateFields 设置允许聚合的数值列
+func WithAggregateFields(fields []string) CrudOption {
+	return func(c *Crud) {
+		c.AggregateFields = fields
+	}
+}
+
+// WithMaxFields 设置客户端通过 fields 参数最多可选择的列表字段与详情字段
+func WithMaxFields(maxFieldOfList, maxFieldOfDetail []string) CrudOption {
+	return func(c *Crud) {
+		c.MaxFieldOfList = maxFieldOfList
+		c.MaxFieldOfDetail = maxFieldOfDetail
+	}
+}
+
+// WithStrictFilters 设置查询参数严格校验模式，可选 StrictFiltersWrite（默认）、StrictFiltersAll 或 StrictFiltersNone
+func WithStrictFilters(mode string) CrudOption {
+	return func(c *Crud) {
+		c.StrictFilters = mode
+	}
+}
+
+// WithSortableFields 设置允许排序的列
+func WithSortableFields(fields []string) CrudOption {
+	return func(c *Crud) {
+		c.SortableFields = fields
+	}
+}
+
+// WithReadOnly 设置只读模式，identityField 为没有主键的视图指定唯一标识记录的列
+func WithReadOnly(readOnly bool, identityField string) CrudOption {
+	return func(c *Crud) {
+		c.ReadOnly = readOnly
+		c.IdentityField = identityField
+	}
+}
+
+// WithDialect 设置 SQL 方言，未设置时默认使用 PostgreSQL
+func WithDialect(d Dialect) CrudOption {
+	return func(c *Crud) {
+		c.Dialect = d
+	}
 }
 
 type QueryBuilder struct {
@@ -94,17 +216,54 @@ type QueryParams struct {
 	ConditionParams []ConditionParam `json:"conditionParams"`
 	OrderBy         []string         `json:"orderBy"`
 	OrderByDesc     []string         `json:"orderByDesc"`
+	WithDeleted     bool             `json:"withDeleted"` // 为 true 时包含软删除的记录
+	Distinct        string           `json:"distinct"`    // count 时只统计该列的不同值
+	UseCursor       bool             `json:"useCursor"`   // 请求中出现 cursor 参数时使用游标分页
+	Cursor          string           `json:"cursor"`      // 上一页返回的游标，为空表示第一页
+	Fields          []string         `json:"fields"`      // 客户端指定返回的字段，为空时使用默认字段
+	Sort            []SortField      `json:"sort"`        // 紧凑排序语法 sort=-created_at:nullsLast,name 解析出的排序项
+	Filter          *ConditionGroup  `json:"filter"`      // query 操作的布尔条件树，与 ConditionParams 以 AND 连接
+	Search          string           `json:"q"`           // 搜索关键字，在 SearchFields 中匹配
+	SearchRank      bool             `json:"rank"`        // 为 true 时先按搜索相关度降序排序
+	Expand          []string         `json:"expand"`      // 需要嵌入的关联名称
 }
 
 type ConditionParam struct {
 	Key    string        `json:"key"`
+	Path   []string      `json:"path,omitempty"` // JSON 列中的路径，为空时比较整列
 	Op     define.OpType `json:"op"`
 	Values any           `json:"values"`
 }
 
+// BatchRowResult 批量写入中单行的执行结果
+type BatchRowResult struct {
+	Index int            `json:"index"`
+	Data  map[string]any `json:"data,omitempty"`
+	Error string         `json:"error,omitempty"`
+}
+
+// UpdateWhereRequest 按条件批量更新的请求，条件来自查询参数，新值来自请求体
+type UpdateWhereRequest struct {
+	QueryParams
+	Values map[string]any `json:"values"`
+	Force  bool           `json:"force"` // 为 true 时允许在没有任何条件的情况下更新全表
+}
+
+// DeleteWhereRequest 按条件删除记录的请求，条件来自查询参数
+type DeleteWhereRequest struct {
+	QueryParams
+	Force bool `json:"force"` // 为 true 时允许在没有任何条件的情况下删除全表
+}
+
+// RestoreRequest 按条件恢复软删除记录的请求，条件来自查询参数
+type RestoreRequest struct {
+	QueryParams
+	Force bool `json:"force"` // 为 true 时允许在没有任何条件的情况下恢复全部已删除记录
+}
+
 // 添加批量删除的请求结构
 type DeleteRequest struct {
-	IDs []any `json:"ids"` // 要删除的记录ID列表，支持字符串和数字类型
+	IDs []any `json:"ids"` // 要删除的记录ID列表，支持字符串和数字类型；复合主键时为键对象，如 {"order_id": 1, "product_id": 2}
 }
 
 func NewQueryBuilder(db *gom.DB, table string) *QueryBuilder {
@@ -188,6 +347,7 @@ func (c *Crud) InitDefaultHandler() error {
 			Method:            http.MethodPost,
 			ParseRequestFunc:  c.requestToMap(),
 			DataOperationFunc: c.saveOperation(),
+			batchOperation:    c.batchOperation(PathSave),
 			RenderResponseFunc: func(ctx *fiber.Ctx, data any, err error) error {
 				if err != nil {
 					return RenderErrs(ctx, err)
@@ -195,10 +355,44 @@ func (c *Crud) InitDefaultHandler() error {
 				return RenderOk(ctx, data)
 			},
 		},
-		PathUpdate: {
+		PathBatchSave: {
+			Method:            http.MethodPost,
+			ParseRequestFunc:  c.requestToMapList(),
+			DataOperationFunc: c.batchSaveOperation(),
+			RenderResponseFunc: func(ctx *fiber.Ctx, data any, err error) error {
+				if err != nil {
+					return RenderErrs(ctx, err)
+				}
+				return RenderOk(ctx, data)
+			},
+		},
+		PathUpsert: {
 			Method:            http.MethodPost,
 			ParseRequestFunc:  c.requestToMap(),
+			DataOperationFunc: c.upsertOperation(),
+			RenderResponseFunc: func(ctx *fiber.Ctx, data any, err error) error {
+				if err != nil {
+					return RenderErrs(ctx, err)
+				}
+				return RenderOk(ctx, data)
+			},
+		},
+		PathUpdate: {
+			Method:            http.MethodPost,
+			ParseRequestFunc:  c.requestToUpdateMap(),
 			DataOperationFunc: c.updateOperation(),
+			batchOperation:    c.batchOperation(PathUpdate),
+			RenderResponseFunc: func(ctx *fiber.Ctx, data any, err error) error {
+				if err != nil {
+					return RenderErrs(ctx, err)
+				}
+				return RenderOk(ctx, data)
+			},
+		},
+		PathUpdateWhere: {
+			Method:            http.MethodPost,
+			ParseRequestFunc:  c.requestToUpdateWhere(),
+			DataOperationFunc: c.updateWhereOperation(),
 			RenderResponseFunc: func(ctx *fiber.Ctx, data any, err error) error {
 				if err != nil {
 					return RenderErrs(ctx, err)
@@ -216,9 +410,35 @@ func (c *Crud) InitDefaultHandler() error {
 				}
 
 				// 回退到查询参数方式
-				return RequestToQueryParamsTransfer(c.Table, c.TransferMap, c.queryBuilder.columnCache)(ctx)
+				params, err := c.queryParamsParser(true)(ctx)
+				if err != nil {
+					return nil, err
+				}
+				return DeleteWhereRequest{QueryParams: params.(QueryParams), Force: ctx.QueryBool("force")}, nil
 			},
 			DataOperationFunc: c.deleteOperation(),
+			batchOperation:    c.batchOperation(PathDelete),
+			RenderResponseFunc: func(ctx *fiber.Ctx, data any, err error) error {
+				if err != nil {
+					return RenderErrs(ctx, err)
+				}
+				return RenderOk(ctx, data)
+			},
+		},
+		PathRestore: {
+			Method: http.MethodPost,
+			ParseRequestFunc: func(ctx *fiber.Ctx) (any, error) {
+				var deleteReq DeleteRequest
+				if err := ctx.BodyParser(&deleteReq); err == nil {
+					return deleteReq, nil
+				}
+				params, err := c.queryParamsParser(true)(ctx)
+				if err != nil {
+					return nil, err
+				}
+				return RestoreRequest{QueryParams: params.(QueryParams), Force: ctx.QueryBool("force")}, nil
+			},
+			DataOperationFunc: c.restoreOperation(),
 			RenderResponseFunc: func(ctx *fiber.Ctx, data any, err error) error {
 				if err != nil {
 					return RenderErrs(ctx, err)
@@ -228,7 +448,7 @@ func (c *Crud) InitDefaultHandler() error {
 		},
 		PathGet: {
 			Method:            http.MethodGet,
-			ParseRequestFunc:  RequestToQueryParamsTransfer(c.Table, c.TransferMap, c.queryBuilder.columnCache),
+			ParseRequestFunc:  c.queryParamsParser(false),
 			DataOperationFunc: c.getOperation(),
 			TransferResultFunc: func(data any) (any, error) {
 				if data == nil {
@@ -244,12 +464,13 @@ func (c *Crud) InitDefaultHandler() error {
 				if err != nil {
 					return RenderErrs(ctx, err)
 				}
+				c.setETag(ctx, data)
 				return RenderOk(ctx, data)
 			},
 		},
 		PathList: {
 			Method:             http.MethodGet,
-			ParseRequestFunc:   RequestToQueryParamsTransfer(c.Table, c.TransferMap, c.queryBuilder.columnCache),
+			ParseRequestFunc:   c.queryParamsParser(false),
 			DataOperationFunc:  c.listOperation(),
 			TransferResultFunc: doNothingTransfer,
 			RenderResponseFunc: func(ctx *fiber.Ctx, data any, err error) error {
@@ -261,7 +482,41 @@ func (c *Crud) InitDefaultHandler() error {
 		},
 		PathPage: {
 			Method:             http.MethodGet,
-			ParseRequestFunc:   RequestToQueryParamsTransfer(c.Table, c.TransferMap, c.queryBuilder.columnCache),
+			ParseRequestFunc:   c.queryParamsParser(false),
+			DataOperationFunc:  c.pageOperation(),
+			TransferResultFunc: doNothingTransfer,
+			RenderResponseFunc: func(ctx *fiber.Ctx, data any, err error) error {
+				if err != nil {
+					return RenderErrs(ctx, err)
+				}
+				return RenderOk(ctx, data)
+			},
+		},
+		PathCount: {
+			Method:            http.MethodGet,
+			ParseRequestFunc:  c.queryParamsParser(false),
+			DataOperationFunc: c.countOperation(),
+			RenderResponseFunc: func(ctx *fiber.Ctx, data any, err error) error {
+				if err != nil {
+					return RenderErrs(ctx, err)
+				}
+				return RenderOk(ctx, data)
+			},
+		},
+		PathAggregate: {
+			Method:            http.MethodGet,
+			ParseRequestFunc:  c.requestToAggregate(),
+			DataOperationFunc: c.aggregateOperation(),
+			RenderResponseFunc: func(ctx *fiber.Ctx, data any, err error) error {
+				if err != nil {
+					return RenderErrs(ctx, err)
+				}
+				return RenderOk(ctx, data)
+			},
+		},
+		PathQuery: {
+			Method:             http.MethodPost,
+			ParseRequestFunc:   c.requestToQuery(),
 			DataOperationFunc:  c.pageOperation(),
 			TransferResultFunc: doNothingTransfer,
 			RenderResponseFunc: func(ctx *fiber.Ctx, data any, err error) error {
@@ -284,6 +539,20 @@ func (c *Crud) InitDefaultHandler() error {
 		},
 	}
 
+	// 只读模式下写操作即使出现在 handlerFilters 中也只返回只读错误
+	if c.ReadOnly {
+		for path, handler := range allHandlers {
+			if contains(ReadOnlyPaths, path) {
+				continue
+			}
+			if len(c.handlerFilters) == 0 {
+				delete(allHandlers, path)
+				continue
+			}
+			handler.DataOperationFunc = c.readOnlyOperation()
+		}
+	}
+
 	// If no filters specified, use all handlers
 	if len(c.handlerFilters) == 0 {
 		for path, handler := range allHandlers {
@@ -302,12 +571,43 @@ func (c *Crud) InitDefaultHandler() error {
 	return nil
 }
 
+// setETag 启用版本控制时，将记录的版本作为 ETag 响应头
+func (c *Crud) setETag(ctx *fiber.Ctx, data any) {
+	if c.VersionField == "" {
+		return
+	}
+	row, ok := data.(map[string]any)
+	if !ok {
+		return
+	}
+	version, ok := row[c.apiFieldName(c.VersionField)]
+	if !ok || version == nil {
+		return
+	}
+	if t, ok := version.(time.Time); ok {
+		version = t.Format(time.RFC3339Nano)
+	}
+	ctx.Set(fiber.HeaderETag, fmt.Sprintf(`"%v"`, version))
+}
+
 func (c *Crud) tableOperation() DataOperationFunc {
 	return func(input any) (any, error) {
 		return c.Db.GetTableInfo(c.Table)
 	}
 }
 
+// queryParamsParser 按严格校验模式返回查询参数解析函数，write 表示该操作会修改数据
+func (c *Crud) queryParamsParser(write bool) ParseRequestFunc {
+	strict := write
+	switch c.StrictFilters {
+	case StrictFiltersAll:
+		strict = true
+	case StrictFiltersNone:
+		strict = false
+	}
+	return requestToQueryParams(c.Table, c.TransferMap, c.queryBuilder.columnCache, strict)
+}
+
 func (c *Crud) requestToMap() ParseRequestFunc {
 	return func(ctx *fiber.Ctx) (any, error) {
 		fmt.Printf("requestToMap: method=%s, path=%s\n", ctx.Method(), ctx.Path())
@@ -332,6 +632,78 @@ func (c *Crud) requestToMap() ParseRequestFunc {
 	}
 }
 
+// requestToUpdateMap 在 requestToMap 的基础上，未在请求体中提供版本时从 If-Match 头读取；
+// Content-Type 为 application/merge-patch+json 时，JSON 列中的对象与已有文档合并
+func (c *Crud) requestToUpdateMap() ParseRequestFunc {
+	parse := c.requestToMap()
+	return func(ctx *fiber.Ctx) (any, error) {
+		input, err := parse(ctx)
+		if err != nil {
+			return input, err
+		}
+		data := input.(map[string]any)
+		if strings.HasPrefix(ctx.Get(fiber.HeaderContentType), MIMEMergePatchJSON) {
+			c.markMergePatch(data)
+		}
+		if c.VersionField == "" {
+			return data, nil
+		}
+		if _, ok := data[c.VersionField]; !ok {
+			if etag := ctx.Get(fiber.HeaderIfMatch); etag != "" {
+				data[c.VersionField] = strings.Trim(strings.TrimPrefix(etag, "W/"), `"`)
+			}
+		}
+		return data, nil
+	}
+}
+
+func (c *Crud) requestToMapList() ParseRequestFunc {
+	return func(ctx *fiber.Ctx) (any, error) {
+		var records []map[string]any
+		if err := ctx.BodyParser(&records); err != nil {
+			return nil, fmt.Errorf("invalid request body: %w", err)
+		}
+		if len(records) == 0 {
+			return nil, errors.New("invalid request body: records cannot be empty")
+		}
+
+		result := make([]map[string]any, len(records))
+		for i, record := range records {
+			data, err := c.transferData(record, false)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = data
+		}
+		return result, nil
+	}
+}
+
+func (c *Crud) requestToUpdateWhere() ParseRequestFunc {
+	parseQuery := c.queryParamsParser(true)
+	return func(ctx *fiber.Ctx) (any, error) {
+		params, err := parseQuery(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		body := make(map[string]any)
+		if err := ctx.BodyParser(&body); err != nil {
+			return nil, fmt.Errorf("invalid request body: %w", err)
+		}
+		values, err := c.transferData(body, false)
+		if err != nil {
+			return nil, err
+		}
+
+		return UpdateWhereRequest{
+			QueryParams: params.(QueryParams),
+			Values:      values,
+			Force:       ctx.QueryBool("force"),
+		}, nil
+	}
+}
+
 func (c *Crud) transferData(input map[string]any, reverse bool) (map[string]any, error) {
 	output := make(map[string]any)
 
@@ -382,6 +754,22 @@ func (c *Crud) transferData(input map[string]any, reverse bool) (map[string]any,
 	return output, nil
 }
 
+// dbFieldName 返回 API 字段对应的数据库字段名
+func (c *Crud) dbFieldName(apiField string) string {
+	if dbField, ok := c.TransferMap[apiField]; ok {
+		return dbField
+	}
+	return apiField
+}
+
+// apiFieldName 返回数据库字段对应的 API 字段名
+func (c *Crud) apiFieldName(dbField string) string {
+	if apiField, ok := c.reverseMap()[dbField]; ok {
+		return apiField
+	}
+	return dbField
+}
+
 func (c *Crud) reverseMap() map[string]string {
 	rm := make(map[string]string)
 	for k, v := range c.TransferMap {
@@ -396,206 +784,652 @@ func (c *Crud) saveOperation() DataOperationFunc {
 		if !ok {
 			return nil, errors.New("invalid data format")
 		}
+		return c.save(nil, data)
+	}
+}
+
+// save 新增一条记录，tx 不为空时在调用方的事务中执行
+func (c *Crud) save(tx *gom.Chain, data map[string]any) (map[string]any, error) {
+	if c.ReadOnly {
+		return nil, c.readOnlyError()
+	}
 
-		chain := c.Db.Chain().Table(c.Table)
+	// 获取表结构信息，包括主键信息
+	tableInfo, err := c.Db.GetTableInfo(c.Table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get table info: %w", err)
+	}
+
+	// 检查表是否有主键
+	if len(tableInfo.PrimaryKeys) == 0 {
+		return nil, errors.New("table has no primary key")
+	}
+
+	// 取出嵌套的一对多子记录
+	children, err := c.takeChildren(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// 处理主键值：自增主键由数据库生成，其余主键列必须由调用方提供
+	if err := checkInsertKeys(tableInfo, data); err != nil {
+		return nil, err
+	}
+
+	if err := c.encodeJSONValues(data); err != nil {
+		return nil, err
+	}
+
+	// 自动填充时间字段
+	c.fillInsertTimeFields(data, time.Now())
+
+	var result map[string]any
+	write := func(chain *gom.Chain) error {
+		// 执行插入操作，按方言取回插入后的数据
+		row, err := insertRow(chain, c.Dialect, c.Table, tableInfo.PrimaryKeys, data)
+		if err != nil {
+			return err
+		}
+		if row == nil {
+			if len(children) > 0 {
+				return errors.New("未找到新增后的数据")
+			}
+			return nil
+		}
+
+		c.decodeJSONColumns(row)
+		childRows, err := c.writeChildren(chain, row, children, false)
+		if err != nil {
+			return err
+		}
+		if result, err = c.transferData(row, true); err != nil {
+			return err
+		}
+		for name, rows := range childRows {
+			result[name] = rows
+		}
+		return nil
+	}
+
+	// 存在嵌套子记录时，父记录与子记录在同一个事务中写入
+	if err := c.runWrite(tx, len(children) > 0, write); err != nil {
+		return nil, err
+	}
+
+	if result == nil {
+		// 如果没有返回数据
+		return map[string]interface{}{
+			"success": true,
+		}, nil
+	}
+	return result, nil
+}
+
+// batchSaveOperation 批量新增记录
+// atomic 模式下在一个事务中插入全部记录，任意一行失败则整体回滚；partial 模式下逐行插入并返回每行的结果
+func (c *Crud) batchSaveOperation() DataOperationFunc {
+	return func(input any) (any, error) {
+		records, ok := input.([]map[string]any)
+		if !ok {
+			return nil, errors.New("invalid data format")
+		}
 
-		// 获取表结构信息，包括主键信息
 		tableInfo, err := c.Db.GetTableInfo(c.Table)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get table info: %w", err)
 		}
-
-		// 检查表是否有主键
 		if len(tableInfo.PrimaryKeys) == 0 {
 			return nil, errors.New("table has no primary key")
 		}
 
-		// 获取第一个主键字段
-		primaryKey := tableInfo.PrimaryKeys[0]
+		now := time.Now()
+		// chain 为空时不加入事务，使用新的查询链写入
+		insert := func(chain *gom.Chain, data map[string]any) (map[string]any, error) {
+			if err := checkInsertKeys(tableInfo, data); err != nil {
+				return nil, err
+			}
+			if err := c.encodeJSONValues(data); err != nil {
+				return nil, err
+			}
+			c.fillInsertTimeFields(data, now)
+			if chain == nil {
+				chain = c.Db.Chain()
+			}
+			row, err := insertRow(chain, c.Dialect, c.Table, tableInfo.PrimaryKeys, data)
+			if err != nil || row == nil {
+				return nil, err
+			}
+			c.decodeJSONColumns(row)
+			return c.transferData(row, true)
+		}
+		return c.saveBatch(records, insert, c.Db.Chain().Transaction)
+	}
+}
+
+// saveBatch 按批量模式写入记录，atomic 模式下通过 transaction 开启事务，partial 模式下以空的 chain 调用 insert
+func (c *Crud) saveBatch(records []map[string]any, insert func(chain *gom.Chain, data map[string]any) (map[string]any, error), transaction func(func(tx *gom.Chain) error) error) (map[string]any, error) {
+	results := make([]BatchRowResult, len(records))
+	failed := 0
+	if c.BatchMode == BatchModePartial {
+		for i, data := range records {
+			results[i].Index = i
+			row, err := insert(nil, data)
+			if err != nil {
+				results[i].Error = err.Error()
+				failed++
+				continue
+			}
+			results[i].Data = row
+		}
+	} else {
+		err := transaction(func(tx *gom.Chain) error {
+			for i, data := range records {
+				row, err := insert(tx, data)
+				if err != nil {
+					return fmt.Errorf("batch save failed at row %d: %w", i, err)
+				}
+				results[i] = BatchRowResult{Index: i, Data: row}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return map[string]interface{}{
+		"success_count": len(records) - failed,
+		"failed_count":  failed,
+		"results":       results,
+	}, nil
+}
 
-		// 检查主键是否是自增的
-		isAutoIncrement := false
+// upsertOperation 按主键或配置的唯一约束新增或更新记录
+func (c *Crud) upsertOperation() DataOperationFunc {
+	return func(input any) (any, error) {
+		data, ok := input.(map[string]any)
+		if !ok {
+			return nil, errors.New("invalid data format")
+		}
+		if err := c.coerceValues(data); err != nil {
+			return nil, err
+		}
 
-		for _, col := range tableInfo.Columns {
-			if col.IsAutoIncrement && col.Name == primaryKey {
-				isAutoIncrement = true
-				break
+		conflictFields := c.ConflictFields
+		if len(conflictFields) == 0 {
+			tableInfo, err := c.Db.GetTableInfo(c.Table)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get table info: %w", err)
+			}
+			if len(tableInfo.PrimaryKeys) == 0 {
+				return nil, errors.New("table has no primary key")
 			}
+			conflictFields = tableInfo.PrimaryKeys
 		}
 
-		// 处理主键值
-		if pkVal, hasPK := data[primaryKey]; hasPK {
-			// 如果提供了主键且值有效，这不应该在保存操作中提供，应使用更新操作
-			if isPrimaryKeyValid(pkVal) {
-				return nil, fmt.Errorf("保存操作不应提供有效的主键，请使用更新操作")
+		// 冲突列的值用于判断记录是否存在，必须提供
+		for _, field := range conflictFields {
+			if val, ok := data[field]; !ok || val == nil {
+				return nil, fmt.Errorf("invalid request body: upsert requires value of %s", field)
 			}
-			// 主键值无效，移除它以便数据库自动生成
-			delete(data, primaryKey)
 		}
 
-		// 如果主键不是自增的且未提供主键，返回错误
-		if !isAutoIncrement {
-			_, hasPK := data[primaryKey]
-			if !hasPK {
-				return nil, errors.New("主键不是自增的，必须提供有效的主键值")
+		now := time.Now()
+		c.fillUpdateTimeFields(data, now)
+
+		// 冲突时覆盖的列，在补充新增时间字段之前确定，避免覆盖创建时间
+		updateFields := c.upsertUpdateFields(data, conflictFields)
+
+		// 版本列由服务端维护：冲突更新时整数版本列自增，时间版本列写入当前时间
+		var extraSets []string
+		if c.VersionField != "" {
+			fields := make([]string, 0, len(updateFields))
+			for _, field := range updateFields {
+				if field != c.VersionField {
+					fields = append(fields, field)
+				}
+			}
+			updateFields = fields
+			if len(updateFields) > 0 {
+				if increment := c.versionIncrement(); increment != "" {
+					extraSets = append(extraSets, increment)
+				} else {
+					data[c.VersionField] = now
+					updateFields = append(updateFields, c.VersionField)
+				}
 			}
 		}
 
-		// 获取表结构信息，用于自动填充时间字段
-		columnInfo, err := c.queryBuilder.CacheTableInfo()
-		if err == nil {
-			now := time.Now()
+		if err := c.encodeJSONValues(data); err != nil {
+			return nil, err
+		}
+		c.fillInsertTimeFields(data, now)
+
+		row, err := upsertRow(c.Db.Chain(), c.Dialect, c.Table, conflictFields, updateFields, extraSets, data)
+		if err != nil {
+			return nil, err
+		}
+		if row == nil {
+			return nil, errors.New("未找到 upsert 后的数据")
+		}
+		c.decodeJSONColumns(row)
+		return c.transferData(row, true)
+	}
+}
+
+// upsertUpdateFields 返回冲突时覆盖的列
+// 配置了 UpsertFields 时只覆盖其中请求提供的列，未提供的列保留原值；否则覆盖请求中除冲突列以外的全部列
+func (c *Crud) upsertUpdateFields(data map[string]any, conflictFields []string) []string {
+	var updateFields []string
+	if len(c.UpsertFields) > 0 {
+		for _, field := range c.UpsertFields {
+			if _, ok := data[field]; ok {
+				updateFields = append(updateFields, field)
+			}
+		}
+		return updateFields
+	}
+
+	conflicts := make(map[string]bool, len(conflictFields))
+	for _, field := range conflictFields {
+		conflicts[field] = true
+	}
+	for field := range data {
+		if !conflicts[field] {
+			updateFields = append(updateFields, field)
+		}
+	}
+	sort.Strings(updateFields)
+	return updateFields
+}
+
+// fillInsertTimeFields 新增记录时自动填充为空或值不合法的时间字段
+func (c *Crud) fillInsertTimeFields(data map[string]any, now time.Time) {
+	columnInfo, err := c.queryBuilder.CacheTableInfo()
+	if err != nil {
+		return
+	}
+
+	// 新增记录时处理所有时间字段
+	for fieldName, colInfo := range columnInfo {
+		// 检查字段是否为时间类型
+		if !isTimeField(colInfo.DataType) {
+			continue
+		}
 
-			// 新增记录时处理所有时间字段
-			for fieldName, colInfo := range columnInfo {
-				// 检查字段是否为时间类型
-				if isTimeField(colInfo.DataType) {
-					// 检查字段是否需要自动填充（为空或值不合法）
-					shouldFill := false
+		// 检查字段是否需要自动填充（为空或值不合法）
+		shouldFill := false
 
-					if fieldVal, exists := data[fieldName]; !exists || fieldVal == nil {
-						// 字段不存在或为nil
+		if fieldVal, exists := data[fieldName]; !exists || fieldVal == nil {
+			// 字段不存在或为nil
+			shouldFill = true
+		} else {
+			// 检查字段值是否为合法的时间值
+			switch v := fieldVal.(type) {
+			case string:
+				if v == "" {
+					shouldFill = true
+				} else {
+					// 尝试解析时间字符串
+					_, err := parseTimeWithMultipleFormats(v)
+					if err != nil {
+						// 时间格式不合法，标记为需要填充
 						shouldFill = true
-					} else {
-						// 检查字段值是否为合法的时间值
-						switch v := fieldVal.(type) {
-						case string:
-							if v == "" {
-								shouldFill = true
-							} else {
-								// 尝试解析时间字符串
-								_, err := parseTimeWithMultipleFormats(v)
-								if err != nil {
-									// 时间格式不合法，标记为需要填充
-									shouldFill = true
-								}
-							}
-						case time.Time:
-							// 已经是time.Time类型，检查是否为零值
-							if v.IsZero() {
-								shouldFill = true
-							}
-						default:
-							// 非时间类型值，标记为需要填充
-							shouldFill = true
-						}
 					}
+				}
+			case time.Time:
+				// 已经是time.Time类型，检查是否为零值
+				if v.IsZero() {
+					shouldFill = true
+				}
+			default:
+				// 非时间类型值，标记为需要填充
+				shouldFill = true
+			}
+		}
 
-					// 如果需要填充，设置为当前时间
-					if shouldFill {
-						data[fieldName] = now
-					}
+		// 如果需要填充，设置为当前时间
+		if shouldFill {
+			data[fieldName] = now
+		}
+	}
+}
+
+// 更新时自动填充的时间字段
+var updateTimeFieldNames = []string{"update_at", "updated_at", "update_time", "modification_time", "modified_at"}
+
+// fillUpdateTimeFields 更新记录时自动填充未提供的更新时间字段
+func (c *Crud) fillUpdateTimeFields(data map[string]any, now time.Time) {
+	columnInfo, err := c.queryBuilder.CacheTableInfo()
+	if err != nil {
+		return
+	}
+
+	for _, fieldName := range updateTimeFieldNames {
+		if col, exists := columnInfo[fieldName]; exists {
+			if isTimeField(col.DataType) {
+				// 显式传入的 null 表示清空该字段，不自动填充
+				if _, hasField := data[fieldName]; !hasField {
+					data[fieldName] = now
 				}
 			}
 		}
+	}
+}
+
+func (c *Crud) updateOperation() DataOperationFunc {
+	return func(input any) (any, error) {
+		data, ok := input.(map[string]any)
+		if !ok {
+			return nil, errors.New("invalid data format")
+		}
+		return c.update(nil, data)
+	}
+}
 
-		// 执行插入操作 - 直接使用原始 SQL 和预处理语句
-		columns := make([]string, 0, len(data))
-		values := make([]any, 0, len(data))
-		placeholders := make([]string, 0, len(data))
+// update 按主键更新一条记录，tx 不为空时在调用方的事务中执行
+func (c *Crud) update(tx *gom.Chain, data map[string]any) (map[string]any, error) {
+	if c.ReadOnly {
+		return nil, c.readOnlyError()
+	}
 
-		i := 1
-		for k, v := range data {
-			columns = append(columns, k)
-			values = append(values, v)
-			placeholders = append(placeholders, fmt.Sprintf("$%d", i))
-			i++
+	// 获取表结构信息，包括主键信息
+	tableInfo, err := c.Db.GetTableInfo(c.Table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get table info: %w", err)
+	}
+
+	// 检查表是否有主键
+	if len(tableInfo.PrimaryKeys) == 0 {
+		return nil, errors.New("table has no primary key")
+	}
+
+	// 检查是否提供了完整且有效的主键，主键列不参与更新
+	keyValues := make(map[string]any, len(tableInfo.PrimaryKeys))
+	for _, primaryKey := range tableInfo.PrimaryKeys {
+		pkVal, hasPK := data[primaryKey]
+		if !hasPK {
+			// 未提供主键，无法执行更新操作
+			return nil, fmt.Errorf("更新操作必须提供有效的主键: %s", primaryKey)
+		}
+		// 如果提供了主键但值无效，直接返回错误
+		if !isPrimaryKeyValid(pkVal) {
+			return nil, fmt.Errorf("提供的主键值无效: %v", pkVal)
+		}
+		keyValues[primaryKey] = pkVal
+		delete(data, primaryKey)
+	}
+
+	// 启用版本控制时必须提供最后读取到的版本，版本列由服务端维护
+	var expectedVersion any
+	if c.VersionField != "" {
+		version, ok := data[c.VersionField]
+		if !ok || version == nil {
+			return nil, fmt.Errorf("invalid request body: update requires current version %s", c.apiFieldName(c.VersionField))
+		}
+		expectedVersion, err = coerceValue(c.queryBuilder.columnCache[c.VersionField], version)
+		if err != nil {
+			return nil, fmt.Errorf("invalid request body: field %s: %w", c.VersionField, err)
 		}
+		delete(data, c.VersionField)
+	}
+
+	// 取出嵌套的一对多子记录
+	children, err := c.takeChildren(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// 按列类型转换请求中的值，显式的 null 只允许写入可空列
+	if err := c.coerceValues(data); err != nil {
+		return nil, err
+	}
+
+	// 只自动填充请求中未出现的更新时间字段
+	now := time.Now()
+	c.fillUpdateTimeFields(data, now)
+
+	// 执行更新操作，只写入请求中出现的字段
+	keyColumns := tableInfo.PrimaryKeys
+	keyArgs := make([]any, len(keyColumns))
+	for i, primaryKey := range keyColumns {
+		keyArgs[i] = keyValues[primaryKey]
+	}
+
+	versionIsTime := c.VersionField != "" && isTimeField(c.queryBuilder.columnCache[c.VersionField].DataType)
+	if versionIsTime {
+		data[c.VersionField] = now
+	}
+
+	var result map[string]any
+	write := func(chain *gom.Chain) error {
+		if len(data) > 0 || c.VersionField != "" {
+			sets, values, err := buildSetClause(c.Dialect, data, 1)
+			if err != nil {
+				return err
+			}
+			if increment := c.versionIncrement(); increment != "" {
+				if sets == "" {
+					sets = increment
+				} else {
+					sets += ", " + increment
+				}
+			}
 
-		// 为 PostgreSQL 使用 RETURNING 语法
-		query := fmt.Sprintf("INSERT INTO \"%s\" (%s) VALUES (%s) RETURNING *",
-			c.Table,
-			strings.Join(columns, ", "),
-			strings.Join(placeholders, ", "))
+			conditions := make([]string, len(keyColumns))
+			for i, primaryKey := range keyColumns {
+				conditions[i] = fmt.Sprintf("%s = %s", c.Dialect.Quote(primaryKey), c.Dialect.Placeholder(len(values)+1))
+				values = append(values, keyArgs[i])
+			}
+			if c.VersionField != "" {
+				conditions = append(conditions, fmt.Sprintf("%s = %s", c.Dialect.Quote(c.VersionField), c.Dialect.Placeholder(len(values)+1)))
+				values = append(values, expectedVersion)
+			}
+
+			query := fmt.Sprintf("UPDATE %s SET %s WHERE %s",
+				c.Dialect.Quote(c.Table), sets, strings.Join(conditions, " AND "))
+			rowsAffected, err := execAffected(chain, query, values...)
+			if err != nil {
+				return err
+			}
 
-		result := chain.Raw(query, values...).Exec()
-		if result.Error != nil {
-			return nil, result.Error
+			// 版本不匹配时区分记录不存在和版本冲突
+			if rowsAffected == 0 && c.VersionField != "" {
+				row, err := selectByKeys(chain, c.Dialect, c.Table, keyColumns, keyArgs)
+				if err != nil {
+					return err
+				}
+				if row == nil {
+					return errors.New("record not found")
+				}
+				return fmt.Errorf("%w: expected %s %v, current %v",
+					ErrVersionConflict, c.apiFieldName(c.VersionField), expectedVersion, row[c.VersionField])
+			}
 		}
 
-		if len(result.Data) == 0 {
-			// 如果没有返回数据
-			return map[string]interface{}{
-				"success": true,
-			}, nil
+		// 重新查询获取更新后的数据
+		row, err := selectByKeys(chain, c.Dialect, c.Table, keyColumns, keyArgs)
+		if err != nil {
+			return err
+		}
+		if row == nil {
+			return errors.New("未找到更新后的数据")
 		}
 
-		return c.transferData(result.Data[0], true)
+		c.decodeJSONColumns(row)
+		childRows, err := c.writeChildren(chain, row, children, true)
+		if err != nil {
+			return err
+		}
+		if result, err = c.transferData(row, true); err != nil {
+			return err
+		}
+		for name, rows := range childRows {
+			result[name] = rows
+		}
+		return nil
+	}
+
+	// 存在嵌套子记录时，父记录与子记录在同一个事务中写入
+	if err := c.runWrite(tx, len(children) > 0, write); err != nil {
+		return nil, err
 	}
+	return result, nil
 }
 
-func (c *Crud) updateOperation() DataOperationFunc {
+// versionIncrement 返回整数版本列自增的 SET 项，未配置版本列或版本列为时间类型时返回空
+// 引用已有值时带上表名，使其同样可以用于 upsert 的冲突更新子句
+func (c *Crud) versionIncrement() string {
+	if c.VersionField == "" || isTimeField(c.queryBuilder.columnCache[c.VersionField].DataType) {
+		return ""
+	}
+	column := c.Dialect.Quote(c.VersionField)
+	return fmt.Sprintf("%s = %s.%s + 1", column, c.Dialect.Quote(c.Table), column)
+}
+
+// runWrite 执行写入：tx 不为空时加入调用方的事务，transactional 为 true 时开启新的事务
+func (c *Crud) runWrite(tx *gom.Chain, transactional bool, write func(chain *gom.Chain) error) error {
+	switch {
+	case tx != nil:
+		return write(tx)
+	case transactional:
+		return c.Db.Chain().Transaction(write)
+	default:
+		return write(c.Db.Chain().Table(c.Table))
+	}
+}
+
+// updateWhereOperation 按查询条件批量更新记录，返回受影响的行数
+func (c *Crud) updateWhereOperation() DataOperationFunc {
 	return func(input any) (any, error) {
-		data, ok := input.(map[string]any)
+		req, ok := input.(UpdateWhereRequest)
 		if !ok {
 			return nil, errors.New("invalid data format")
 		}
+		// 版本列由服务端维护：整数版本列自增，时间版本列写入当前时间
+		if c.VersionField != "" {
+			delete(req.Values, c.VersionField)
+		}
+		if len(req.Values) == 0 {
+			return nil, errors.New("invalid request body: values cannot be empty")
+		}
 
-		chain := c.Db.Chain().Table(c.Table)
+		if err := c.coerceValues(req.Values); err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		c.fillUpdateTimeFields(req.Values, now)
+		increment := c.versionIncrement()
+		if c.VersionField != "" && increment == "" {
+			req.Values[c.VersionField] = now
+		}
 
-		// 获取表结构信息，包括主键信息
-		tableInfo, err := c.Db.GetTableInfo(c.Table)
+		sets, values, err := buildSetClause(c.Dialect, req.Values, 1)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get table info: %w", err)
+			return nil, err
+		}
+		if increment != "" {
+			sets += ", " + increment
 		}
 
-		// 检查表是否有主键
-		if len(tableInfo.PrimaryKeys) == 0 {
-			return nil, errors.New("table has no primary key")
+		// 无法转换的条件不能被忽略，否则会扩大更新范围
+		where, condValues, err := c.whereClause(req.QueryParams, len(values)+1)
+		if err != nil {
+			return nil, err
 		}
+		values = append(values, condValues...)
 
-		// 获取第一个主键字段
-		primaryKey := tableInfo.PrimaryKeys[0]
+		// 没有任何条件时必须显式指定 force，避免误更新全表
+		if len(req.ConditionParams) == 0 && !req.Force {
+			return nil, errors.New("invalid request body: updateWhere requires at least one condition, set force=true to update all rows")
+		}
 
-		// 检查是否提供了有效的主键
-		var primaryKeyValue any
-		if pkVal, hasPK := data[primaryKey]; hasPK {
-			// 如果提供了主键但值无效，直接返回错误
-			if !isPrimaryKeyValid(pkVal) {
-				return nil, fmt.Errorf("提供的主键值无效: %v", pkVal)
-			}
-			primaryKeyValue = pkVal
-			delete(data, primaryKey)
-		} else {
-			// 未提供主键，无法执行更新操作
-			return nil, errors.New("更新操作必须提供有效的主键")
+		query := fmt.Sprintf("UPDATE %s SET %s", c.Dialect.Quote(c.Table), sets)
+		if where != "" {
+			query += " WHERE " + where
 		}
 
-		// 获取表结构信息，用于自动填充时间字段
-		columnInfo, err := c.queryBuilder.CacheTableInfo()
-		if err == nil {
-			now := time.Now()
-
-			// 更新操作时，只自动填充更新时间相关字段
-			updateTimeFieldNames := []string{"update_at", "updated_at", "update_time", "modification_time", "modified_at"}
-			for _, fieldName := range updateTimeFieldNames {
-				if col, exists := columnInfo[fieldName]; exists {
-					if isTimeField(col.DataType) {
-						if _, hasField := data[fieldName]; !hasField || data[fieldName] == nil {
-							data[fieldName] = now
-						}
-					}
-				}
-			}
+		rowsAffected, err := execAffected(c.Db.Chain(), query, values...)
+		if err != nil {
+			return nil, fmt.Errorf("update failed: %w", err)
 		}
 
-		// 执行更新操作
-		chain.Where(primaryKey, define.OpEq, primaryKeyValue)
-		result := chain.Values(data).Update()
-		if result.Error != nil {
-			return nil, result.Error
+		return map[string]interface{}{
+			"updated_count": rowsAffected,
+		}, nil
+	}
+}
+
+// buildSetClause 构建 UPDATE 语句的 SET 子句，参数占位符从 startIndex 开始
+// JSON 合并补丁的值按方言生成合并表达式，而不是整体覆盖
+func buildSetClause(d Dialect, data map[string]any, startIndex int) (string, []any, error) {
+	fields := make([]string, 0, len(data))
+	for field := range data {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	sets := make([]string, len(fields))
+	values := make([]any, 0, len(fields))
+	for i, field := range fields {
+		if patch, ok := data[field].(jsonMergePatch); ok {
+			expr, patchValues, err := d.JSONMergePatch(d.Quote(field), patch, startIndex+len(values))
+			if err != nil {
+				return "", nil, fmt.Errorf("invalid request body: field %s: %w", field, err)
+			}
+			sets[i] = fmt.Sprintf("%s = %s", d.Quote(field), expr)
+			values = append(values, patchValues...)
+			continue
 		}
+		sets[i] = fmt.Sprintf("%s = %s", d.Quote(field), d.Placeholder(startIndex+len(values)))
+		values = append(values, data[field])
+	}
+	return strings.Join(sets, ", "), values, nil
+}
 
-		// 重新查询获取更新后的数据
-		queryResult := chain.First()
-		if queryResult.Error != nil {
-			return nil, queryResult.Error
+// coerceValues 按缓存的列信息转换写入值的类型
+// 未知列返回错误；显式的 nil 只允许写入可空列；JSON 数字、布尔和字符串按 TransferType 转换为列类型
+func (c *Crud) coerceValues(data map[string]any) error {
+	columnInfo, err := c.queryBuilder.CacheTableInfo()
+	if err != nil {
+		return fmt.Errorf("failed to get table info: %w", err)
+	}
+
+	for field, value := range data {
+		column, ok := columnInfo[field]
+		if !ok {
+			return fmt.Errorf("invalid request body: unknown field %s", field)
 		}
-		if len(queryResult.Data) == 0 {
-			return nil, errors.New("未找到更新后的数据")
+		converted, err := coerceValue(column, value)
+		if err != nil {
+			return fmt.Errorf("invalid request body: field %s: %w", field, err)
 		}
+		data[field] = converted
+	}
+	return nil
+}
 
-		return c.transferData(queryResult.Data[0], true)
+// coerceValue 将请求体中的单个值转换为列对应的类型
+func coerceValue(column define.ColumnInfo, value any) (any, error) {
+	if isJSONColumn(column) {
+		return encodeJSONValue(column, value)
+	}
+	switch v := value.(type) {
+	case nil:
+		if !column.IsNullable {
+			return nil, errors.New("column is not nullable")
+		}
+		return nil, nil
+	case string:
+		return TransferType(column)(v)
+	case float64:
+		return TransferType(column)(strconv.FormatFloat(v, 'f', -1, 64))
+	case json.Number:
+		return TransferType(column)(v.String())
+	case bool:
+		return TransferType(column)(strconv.FormatBool(v))
+	default:
+		return value, nil
 	}
 }
 
@@ -622,6 +1456,37 @@ func isPrimaryKeyValid(value any) bool {
 	}
 }
 
+// checkInsertKeys 检查新增记录时的主键值
+// 自增主键不应提供有效值（无效值会被移除以便数据库生成），非自增主键列必须提供有效值
+func checkInsertKeys(tableInfo *define.TableInfo, data map[string]any) error {
+	autoKeys := make(map[string]bool)
+	for _, col := range tableInfo.Columns {
+		if col.IsAutoIncrement {
+			autoKeys[col.Name] = true
+		}
+	}
+
+	for _, primaryKey := range tableInfo.PrimaryKeys {
+		pkVal, hasPK := data[primaryKey]
+		if autoKeys[primaryKey] {
+			if hasPK {
+				// 如果提供了主键且值有效，这不应该在保存操作中提供，应使用更新操作
+				if isPrimaryKeyValid(pkVal) {
+					return fmt.Errorf("保存操作不应提供有效的主键，请使用更新操作")
+				}
+				// 主键值无效，移除它以便数据库自动生成
+				delete(data, primaryKey)
+			}
+			continue
+		}
+		// 如果主键不是自增的且未提供主键，返回错误
+		if !hasPK || !isPrimaryKeyValid(pkVal) {
+			return fmt.Errorf("主键 %s 不是自增的，必须提供有效的主键值", primaryKey)
+		}
+	}
+	return nil
+}
+
 // 尝试使用多种格式解析时间字符串
 func parseTimeWithMultipleFormats(v string) (time.Time, error) {
 	timeFormats := []string{
@@ -652,145 +1517,342 @@ func parseTimeWithMultipleFormats(v string) (time.Time, error) {
 		time.StampNano,
 	}
 
-	var lastErr error
-	for _, format := range timeFormats {
-		parsed, err := time.Parse(format, v)
-		if err == nil {
-			return parsed, nil
+	var lastErr error
+	for _, format := range timeFormats {
+		parsed, err := time.Parse(format, v)
+		if err == nil {
+			return parsed, nil
+		}
+		lastErr = err
+	}
+
+	return time.Time{}, fmt.Errorf("无法解析为时间格式: %s (错误: %v)", v, lastErr)
+}
+
+// 修改 deleteOperation 方法
+func (c *Crud) deleteOperation() DataOperationFunc {
+	return func(input any) (any, error) {
+		return c.deleteRecords(nil, input)
+	}
+}
+
+// deleteRecords 按批量删除请求或查询参数删除记录，tx 不为空时在调用方的事务中执行
+func (c *Crud) deleteRecords(tx *gom.Chain, input any) (map[string]any, error) {
+	if c.ReadOnly {
+		return nil, c.readOnlyError()
+	}
+
+	// 没有 ids 与任何条件时必须显式指定 force，避免误删全表
+	deleteAll := errors.New("invalid request body: delete requires ids or at least one condition, set force=true to delete all rows")
+	switch req := input.(type) {
+	case DeleteWhereRequest:
+		if len(req.ConditionParams) == 0 && !req.Force {
+			return nil, deleteAll
+		}
+		input = req.QueryParams
+	case QueryParams:
+		if len(req.ConditionParams) == 0 {
+			return nil, deleteAll
+		}
+	}
+
+	// 软删除时第一个占位符用于设置删除标记
+	startIndex := 1
+	if c.SoftDeleteField != "" {
+		startIndex = 2
+	}
+
+	condition, values, err := c.deleteCondition(input, startIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	var query string
+	if c.SoftDeleteField != "" {
+		deletedValue, _ := c.softDeleteValues()
+		conditions := []string{c.notDeletedCondition()}
+		if condition != "" {
+			conditions = append(conditions, "("+condition+")")
+		}
+		query = fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s",
+			c.Dialect.Quote(c.Table),
+			c.Dialect.Quote(c.SoftDeleteField),
+			c.Dialect.Placeholder(1),
+			strings.Join(conditions, " AND "))
+		values = append([]any{deletedValue}, values...)
+	} else {
+		// 使用 DELETE 语句但不带 RETURNING
+		query = fmt.Sprintf("DELETE FROM %s", c.Dialect.Quote(c.Table))
+		if condition != "" {
+			query += " WHERE " + condition
 		}
-		lastErr = err
 	}
 
-	return time.Time{}, fmt.Errorf("无法解析为时间格式: %s (错误: %v)", v, lastErr)
+	if tx == nil {
+		tx = c.Db.Chain()
+	}
+	rowsAffected, err := execAffected(tx, query, values...)
+	if err != nil {
+		return nil, fmt.Errorf("delete failed: %w", err)
+	}
+
+	result := map[string]interface{}{
+		"deleted_count": rowsAffected,
+	}
+	if deleteReq, ok := input.(DeleteRequest); ok {
+		result["ids"] = deleteReq.IDs
+	}
+	return result, nil
 }
 
-// 修改 deleteOperation 方法
-func (c *Crud) deleteOperation() DataOperationFunc {
+// restoreOperation 恢复软删除的记录，参数与 delete 相同
+func (c *Crud) restoreOperation() DataOperationFunc {
 	return func(input any) (any, error) {
-		// 获取表的主键信息
-		tableInfo, err := c.Db.GetTableInfo(c.Table)
+		if c.SoftDeleteField == "" {
+			return nil, errors.New("soft delete is not enabled for this table")
+		}
+
+		// 没有 ids 与任何条件时必须显式指定 force，避免误恢复全部已删除记录
+		restoreAll := errors.New("invalid request body: restore requires ids or at least one condition, set force=true to restore all rows")
+		switch req := input.(type) {
+		case RestoreRequest:
+			if len(req.ConditionParams) == 0 && !req.Force {
+				return nil, restoreAll
+			}
+			input = req.QueryParams
+		case QueryParams:
+			if len(req.ConditionParams) == 0 {
+				return nil, restoreAll
+			}
+		}
+
+		condition, values, err := c.deleteCondition(input, 2)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get table info: %w", err)
+			return nil, err
 		}
 
-		// 查找主键列
-		primaryKey := tableInfo.PrimaryKeys[0]
-		if primaryKey == "" {
-			return nil, errors.New("table has no primary key")
+		_, restoredValue := c.softDeleteValues()
+		conditions := []string{"NOT (" + c.notDeletedCondition() + ")"}
+		if condition != "" {
+			conditions = append(conditions, "("+condition+")")
 		}
+		query := fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s",
+			c.Dialect.Quote(c.Table),
+			c.Dialect.Quote(c.SoftDeleteField),
+			c.Dialect.Placeholder(1),
+			strings.Join(conditions, " AND "))
+		values = append([]any{restoredValue}, values...)
 
-		// 批量删除模式
+		rowsAffected, err := execAffected(c.Db.Chain(), query, values...)
+		if err != nil {
+			return nil, fmt.Errorf("restore failed: %w", err)
+		}
+
+		result := map[string]interface{}{
+			"restored_count": rowsAffected,
+		}
 		if deleteReq, ok := input.(DeleteRequest); ok {
-			if len(deleteReq.IDs) == 0 {
-				return nil, errors.New("ids cannot be empty")
-			}
+			result["ids"] = deleteReq.IDs
+		}
+		return result, nil
+	}
+}
 
-			// 批量删除 - 构建 WHERE primaryKey IN (...) 条件
-			placeholders := make([]string, len(deleteReq.IDs))
-			values := make([]any, len(deleteReq.IDs))
-			for i, id := range deleteReq.IDs {
-				placeholders[i] = fmt.Sprintf("$%d", i+1)
-				values[i] = id
-			}
+// deleteCondition 根据批量删除请求或查询参数构建 WHERE 条件（不含 WHERE 关键字）
+func (c *Crud) deleteCondition(input any, startIndex int) (string, []any, error) {
+	// 获取表的主键信息
+	tableInfo, err := c.Db.GetTableInfo(c.Table)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get table info: %w", err)
+	}
+
+	// 检查表是否有主键
+	if len(tableInfo.PrimaryKeys) == 0 {
+		return "", nil, errors.New("table has no primary key")
+	}
 
-			query := fmt.Sprintf("DELETE FROM \"%s\" WHERE \"%s\" IN (%s)",
-				c.Table,
-				primaryKey,
-				strings.Join(placeholders, ", "))
+	// 批量删除模式
+	if deleteReq, ok := input.(DeleteRequest); ok {
+		if len(deleteReq.IDs) == 0 {
+			return "", nil, errors.New("ids cannot be empty")
+		}
 
-			result := c.Db.Chain().Raw(query, values...).Exec()
-			if result.Error != nil {
-				return nil, fmt.Errorf("batch delete failed: %w", result.Error)
-			}
+		// 批量删除 - 按主键元组构建 WHERE 条件
+		return c.keyCondition(tableInfo.PrimaryKeys, deleteReq.IDs, startIndex)
+	}
 
-			// Call the RowsAffected function to get the actual count
-			rowsAffected, err := result.RowsAffected()
-			if err != nil {
-				return nil, fmt.Errorf("failed to get affected rows: %w", err)
-			}
+	// 单个ID或条件删除模式
+	params, ok := input.(QueryParams)
+	if !ok {
+		return "", nil, errors.New("invalid delete parameters")
+	}
 
-			return map[string]interface{}{
-				"deleted_count": rowsAffected,
-				"ids":           deleteReq.IDs,
-			}, nil
-		}
+	values := make([]any, 0)
+	var conditions []string
 
-		// 单个ID或条件删除模式
-		params, ok := input.(QueryParams)
-		if !ok {
-			return nil, errors.New("invalid delete parameters")
+	valueIndex := startIndex
+	for _, v := range params.ConditionParams {
+		condition, condValues := buildCondition(c.Dialect, v, valueIndex)
+		if condition == "" {
+			// 无法转换的条件不能被忽略，否则会扩大删除范围
+			return "", nil, fmt.Errorf("invalid request body: unsupported condition on %s", v.Key)
 		}
+		conditions = append(conditions, condition)
+		values = append(values, condValues...)
+		valueIndex += len(condValues)
+	}
 
-		// 使用 DELETE 语句但不带 RETURNING
-		query := fmt.Sprintf("DELETE FROM \"%s\"", c.Table)
-		values := make([]any, 0)
-		var conditions []string
-
-		valueIndex := 1
-		for _, v := range params.ConditionParams {
-			condition, condValues := buildCondition(v, valueIndex)
-			if condition != "" {
-				conditions = append(conditions, condition)
-				values = append(values, condValues...)
-				valueIndex += len(condValues)
+	return strings.Join(conditions, " AND "), values, nil
+}
+
+// softDeleteValues 返回软删除列在删除和恢复时写入的值
+// 时间列删除时写入当前时间、恢复时写入 NULL；布尔列分别写入 true 和 false
+func (c *Crud) softDeleteValues() (deleted any, restored any) {
+	column := c.queryBuilder.columnCache[c.SoftDeleteField]
+	if isTimeField(column.DataType) {
+		return time.Now(), nil
+	}
+	return true, false
+}
+
+// notDeletedCondition 返回排除软删除记录的原始 SQL 条件
+func (c *Crud) notDeletedCondition() string {
+	column := c.queryBuilder.columnCache[c.SoftDeleteField]
+	if isTimeField(column.DataType) {
+		return c.Dialect.Quote(c.SoftDeleteField) + " IS NULL"
+	}
+	return c.Dialect.Quote(c.SoftDeleteField) + " IS NOT TRUE"
+}
+
+// keyCondition 根据主键值列表构建匹配条件
+// 单主键时 ids 可以是标量或键对象，生成 IN 条件；复合主键时 ids 必须是包含全部主键列的键对象
+func (c *Crud) keyCondition(primaryKeys []string, ids []any, startIndex int) (string, []any, error) {
+	keys := make([]map[string]any, len(ids))
+	for i, id := range ids {
+		if obj, ok := id.(map[string]any); ok {
+			mapped, err := c.transferData(obj, false)
+			if err != nil {
+				return "", nil, err
 			}
+			keys[i] = mapped
+		} else if len(primaryKeys) == 1 {
+			keys[i] = map[string]any{primaryKeys[0]: id}
+		} else {
+			return "", nil, fmt.Errorf("invalid request body: composite primary key requires key objects, got %v", id)
 		}
-
-		if len(conditions) > 0 {
-			query += " WHERE " + strings.Join(conditions, " AND ")
+		for _, pk := range primaryKeys {
+			if _, ok := keys[i][pk]; !ok {
+				return "", nil, fmt.Errorf("invalid request body: key object missing primary key %s", pk)
+			}
 		}
+	}
 
-		result := c.Db.Chain().Raw(query, values...).Exec()
-		if result.Error != nil {
-			return nil, fmt.Errorf("delete failed: %w", result.Error)
+	if len(primaryKeys) == 1 {
+		values := make([]any, len(keys))
+		for i, key := range keys {
+			values[i] = key[primaryKeys[0]]
 		}
+		return fmt.Sprintf("%s IN (%s)",
+			c.Dialect.Quote(primaryKeys[0]),
+			strings.Join(placeholders(c.Dialect, startIndex, len(values)), ", ")), values, nil
+	}
 
-		// Call the RowsAffected function to get the actual count
-		rowsAffected, err := result.RowsAffected()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get affected rows: %w", err)
+	tuples := make([]string, len(keys))
+	values := make([]any, 0, len(keys)*len(primaryKeys))
+	for i, key := range keys {
+		parts := make([]string, len(primaryKeys))
+		for j, pk := range primaryKeys {
+			parts[j] = fmt.Sprintf("%s = %s", c.Dialect.Quote(pk), c.Dialect.Placeholder(startIndex+len(values)))
+			values = append(values, key[pk])
 		}
-
-		return map[string]interface{}{
-			"deleted_count": rowsAffected,
-		}, nil
+		tuples[i] = "(" + strings.Join(parts, " AND ") + ")"
 	}
+	return strings.Join(tuples, " OR "), values, nil
 }
 
 // 构建 SQL 条件
-func buildCondition(param ConditionParam, startIndex int) (string, []any) {
+func buildCondition(d Dialect, param ConditionParam, startIndex int) (string, []any) {
 	var condition string
 	var values []any
 
+	key := d.Quote(param.Key)
+	if len(param.Path) > 0 {
+		key = d.JSONExtract(key, param.Path, isNumericCondition(param.Values))
+	}
 	switch param.Op {
 	case define.OpEq:
-		condition = fmt.Sprintf("%s = $%d", param.Key, startIndex)
+		condition = fmt.Sprintf("%s = %s", key, d.Placeholder(startIndex))
 		values = []any{param.Values}
 	case define.OpNe:
-		condition = fmt.Sprintf("%s != $%d", param.Key, startIndex)
+		condition = fmt.Sprintf("%s != %s", key, d.Placeholder(startIndex))
 		values = []any{param.Values}
 	case define.OpGt:
-		condition = fmt.Sprintf("%s > $%d", param.Key, startIndex)
+		condition = fmt.Sprintf("%s > %s", key, d.Placeholder(startIndex))
 		values = []any{param.Values}
 	case define.OpGe:
-		condition = fmt.Sprintf("%s >= $%d", param.Key, startIndex)
+		condition = fmt.Sprintf("%s >= %s", key, d.Placeholder(startIndex))
 		values = []any{param.Values}
 	case define.OpLt:
-		condition = fmt.Sprintf("%s < $%d", param.Key, startIndex)
+		condition = fmt.Sprintf("%s < %s", key, d.Placeholder(startIndex))
 		values = []any{param.Values}
 	case define.OpLe:
-		condition = fmt.Sprintf("%s <= $%d", param.Key, startIndex)
+		condition = fmt.Sprintf("%s <= %s", key, d.Placeholder(startIndex))
+		values = []any{param.Values}
+	case define.OpIn, define.OpNotIn:
+		// 处理 IN 操作，单个值按只有一个元素的列表处理
+		vals := conditionValues(param.Values)
+		if len(vals) == 0 {
+			return "", nil
+		}
+		op := "IN"
+		if param.Op == define.OpNotIn {
+			op = "NOT IN"
+		}
+		condition = fmt.Sprintf("%s %s (%s)", key, op, strings.Join(placeholders(d, startIndex, len(vals)), ", "))
+		values = vals
+	case define.OpIsNull:
+		condition = fmt.Sprintf("%s IS NULL", key)
+	case define.OpIsNotNull:
+		condition = fmt.Sprintf("%s IS NOT NULL", key)
+	case define.OpBetween, define.OpNotBetween:
+		vals := conditionValues(param.Values)
+		if len(vals) != 2 {
+			return "", nil
+		}
+		op := "BETWEEN"
+		if param.Op == define.OpNotBetween {
+			op = "NOT BETWEEN"
+		}
+		condition = fmt.Sprintf("%s %s %s AND %s", key, op, d.Placeholder(startIndex), d.Placeholder(startIndex+1))
+		values = vals
+	case define.OpLike:
+		condition = fmt.Sprintf("%s LIKE %s", key, d.Placeholder(startIndex))
+		values = []any{param.Values}
+	case define.OpNotLike:
+		condition = fmt.Sprintf("%s NOT LIKE %s", key, d.Placeholder(startIndex))
+		values = []any{param.Values}
+	case OpILike:
+		condition = d.ILike(key, d.Placeholder(startIndex))
+		values = []any{param.Values}
+	case OpStartsWith, OpEndsWith, OpContains:
+		text, ok := param.Values.(string)
+		if !ok {
+			return "", nil
+		}
+		pattern := escapeLike(text)
+		switch param.Op {
+		case OpStartsWith:
+			pattern += "%"
+		case OpEndsWith:
+			pattern = "%" + pattern
+		default:
+			pattern = "%" + pattern + "%"
+		}
+		condition = d.ILike(key, d.Placeholder(startIndex)) + " ESCAPE '" + likeEscape + "'"
+		values = []any{pattern}
+	case OpJSONContains:
+		condition = d.JSONContains(key, d.Placeholder(startIndex))
 		values = []any{param.Values}
-	case define.OpIn:
-		// 处理 IN 操作
-		if vals, ok := param.Values.([]any); ok && len(vals) > 0 {
-			placeholders := make([]string, len(vals))
-			for i := range vals {
-				placeholders[i] = fmt.Sprintf("$%d", startIndex+i)
-			}
-			condition = fmt.Sprintf("%s IN (%s)", param.Key, strings.Join(placeholders, ", "))
-			values = vals
-		}
 	default:
 		// 对于其他操作，暂时不处理
 		return "", nil
@@ -799,6 +1861,140 @@ func buildCondition(param ConditionParam, startIndex int) (string, []any) {
 	return condition, values
 }
 
+// likeEscape LIKE 模式中使用的转义字符，避免反斜杠在不同数据库字符串字面量中的差异
+const likeEscape = "!"
+
+// escapeLike 转义 LIKE 模式中的通配符与转义字符本身
+func escapeLike(s string) string {
+	return strings.NewReplacer(likeEscape, likeEscape+likeEscape, "%", likeEscape+"%", "_", likeEscape+"_").Replace(s)
+}
+
+// conditionValues 将条件值统一转换为列表
+func conditionValues(value any) []any {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []any:
+		return v
+	default:
+		return []any{v}
+	}
+}
+
+// whereClause 根据查询参数构建完整的 WHERE 条件（不含 WHERE 关键字），包含软删除过滤
+// 无法转换为 SQL 的条件会返回错误，而不是被忽略
+func (c *Crud) whereClause(params QueryParams, startIndex int) (string, []any, error) {
+	var conditions []string
+	var values []any
+	for _, v := range params.ConditionParams {
+		condition, condValues := buildCondition(c.Dialect, v, startIndex+len(values))
+		if condition == "" {
+			return "", nil, fmt.Errorf("invalid request body: unsupported condition on %s", v.Key)
+		}
+		conditions = append(conditions, condition)
+		values = append(values, condValues...)
+	}
+	if params.Filter != nil {
+		condition, filterValues, err := buildGroupCondition(c.Dialect, *params.Filter, startIndex+len(values))
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, condition)
+		values = append(values, filterValues...)
+	}
+	if params.Search != "" {
+		condition, searchValues, err := c.searchCondition(params.Search, startIndex+len(values))
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, condition)
+		values = append(values, searchValues...)
+	}
+	if c.SoftDeleteField != "" && !params.WithDeleted {
+		conditions = append(conditions, c.notDeletedCondition())
+	}
+	return strings.Join(conditions, " AND "), values, nil
+}
+
+// countOperation 统计满足条件的记录数，指定 distinct 时统计该列的不同值个数
+func (c *Crud) countOperation() DataOperationFunc {
+	return func(input any) (any, error) {
+		params, ok := input.(QueryParams)
+		if !ok {
+			params = QueryParams{
+				Table: c.Table,
+			}
+		}
+
+		countExpr := "COUNT(*)"
+		if params.Distinct != "" {
+			if _, ok := c.queryBuilder.columnCache[params.Distinct]; !ok {
+				return nil, fmt.Errorf("invalid request body: unknown distinct field %s", params.Distinct)
+			}
+			countExpr = fmt.Sprintf("COUNT(DISTINCT %s)", c.Dialect.Quote(params.Distinct))
+		}
+
+		total, err := c.countRows(params, countExpr)
+		if err != nil {
+			return nil, fmt.Errorf("count failed: %w", err)
+		}
+		return total, nil
+	}
+}
+
+// toInt64 将驱动返回的数值转换为 int64
+func toInt64(value any) (int64, error) {
+	switch v := value.(type) {
+	case nil:
+		return 0, nil
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case uint64:
+		return int64(v), nil
+	case float64:
+		return int64(v), nil
+	case []byte:
+		return strconv.ParseInt(string(v), 10, 64)
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	default:
+		return 0, fmt.Errorf("unexpected numeric type: %T", value)
+	}
+}
+
+// resolveFields 计算查询返回的字段
+// 未指定 fields 时使用默认字段；指定时不存在的列返回错误，并与允许的最大字段集合取交集
+// 最大字段集合为空时以默认字段为上限，只有默认字段也为空时才允许全部列
+func (c *Crud) resolveFields(requested, defaults, allowed []string) ([]string, error) {
+	if len(requested) == 0 {
+		return defaults, nil
+	}
+	if len(allowed) == 0 {
+		allowed = defaults
+	}
+
+	fields := make([]string, 0, len(requested))
+	for _, field := range requested {
+		if _, ok := c.queryBuilder.columnCache[field]; !ok {
+			return nil, fmt.Errorf("invalid request body: unknown field %s", c.apiFieldName(field))
+		}
+		if len(allowed) > 0 && !contains(allowed, field) {
+			continue
+		}
+		if !contains(fields, field) {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, errors.New("invalid request body: none of the requested fields are allowed")
+	}
+	return fields, nil
+}
+
 // 修改 getOperation 方法
 func (c *Crud) getOperation() DataOperationFunc {
 	return func(input any) (any, error) {
@@ -810,29 +2006,31 @@ func (c *Crud) getOperation() DataOperationFunc {
 			}
 		}
 
-		chain := c.Db.Chain().Table(c.Table)
-		for _, v := range params.ConditionParams {
-			chain.Where(v.Key, v.Op, v.Values)
+		if err := c.checkIdentityCondition(params); err != nil {
+			return nil, err
 		}
-		if len(c.FieldOfDetail) > 0 {
-			chain.Fields(c.FieldOfDetail...)
+
+		fields, err := c.resolveFields(params.Fields, c.FieldOfDetail, c.MaxFieldOfDetail)
+		if err != nil {
+			return nil, err
 		}
 
-		result := chain.First()
-		if result.Error != nil {
-			// 对于"没有行"的情况返回空对象而不是错误
-			if strings.Contains(result.Error.Error(), "no rows") {
-				return map[string]interface{}{}, nil
+		rows, err := c.selectRows(params, fields, 1, 0)
+		if err != nil {
+			var paramsErr *InvalidParamsError
+			if errors.As(err, &paramsErr) {
+				return nil, err
 			}
-			return nil, fmt.Errorf("get failed: %w", result.Error)
+			return nil, fmt.Errorf("get failed: %w", err)
 		}
 
-		if len(result.Data) == 0 {
+		// 对于"没有行"的情况返回空对象而不是错误
+		if len(rows) == 0 {
 			return map[string]interface{}{}, nil
 		}
 
 		// 转换字段名称
-		return c.transferData(result.Data[0], true)
+		return c.transferData(rows[0], true)
 	}
 }
 func (c *Crud) pageOperation() DataOperationFunc {
@@ -847,10 +2045,11 @@ func (c *Crud) pageOperation() DataOperationFunc {
 			}
 		}
 
-		chain := c.Db.Chain().Table(c.Table)
-		for _, v := range params.ConditionParams {
-			chain.Where(v.Key, v.Op, v.Values)
+		// 游标分页模式
+		if params.UseCursor {
+			return c.cursorPage(params)
 		}
+
 		page := params.Page
 		pageSize := params.PageSize
 		if pageSize == 0 {
@@ -859,20 +2058,32 @@ func (c *Crud) pageOperation() DataOperationFunc {
 		if page == 0 {
 			page = 1
 		}
-		if len(params.OrderBy) > 0 {
-			for _, v := range params.OrderBy {
-				chain.OrderBy(v)
-			}
-		}
-		if len(params.OrderByDesc) > 0 {
-			for _, v := range params.OrderByDesc {
-				chain.OrderByDesc(v)
-			}
+		fields, err := c.resolveFields(params.Fields, c.FieldOfList, c.MaxFieldOfList)
+		if err != nil {
+			return nil, err
 		}
-		if len(c.FieldOfList) > 0 {
-			chain.Fields(c.FieldOfList...)
+
+		total, err := c.countRows(params, "")
+		if err != nil {
+			return nil, fmt.Errorf("page failed: %w", err)
 		}
-		return chain.Page(page, pageSize).PageInfo()
+		rows, err := c.selectRows(params, fields, pageSize, (page-1)*pageSize)
+		if err != nil {
+			return nil, fmt.Errorf("page failed: %w", err)
+		}
+
+		pages := int((total + int64(pageSize) - 1) / int64(pageSize))
+		return &PageInfo{
+			PageNum:     page,
+			PageSize:    pageSize,
+			Total:       total,
+			Pages:       pages,
+			HasPrev:     page > 1,
+			HasNext:     page < pages,
+			List:        rows,
+			IsFirstPage: page == 1,
+			IsLastPage:  page >= pages,
+		}, nil
 	}
 }
 
@@ -887,28 +2098,20 @@ func (c *Crud) listOperation() DataOperationFunc {
 			}
 		}
 
-		chain := c.Db.Chain().Table(c.Table)
-		for _, v := range params.ConditionParams {
-			chain.Where(v.Key, v.Op, v.Values)
-		}
-		if len(params.OrderBy) > 0 {
-			for _, v := range params.OrderBy {
-				chain.OrderBy(v)
-			}
-		}
-		if len(params.OrderByDesc) > 0 {
-			for _, v := range params.OrderByDesc {
-				chain.OrderByDesc(v)
-			}
+		// 游标分页模式
+		if params.UseCursor {
+			return c.cursorPage(params)
 		}
-		if len(c.FieldOfList) > 0 {
-			chain.Fields(c.FieldOfList...)
+
+		fields, err := c.resolveFields(params.Fields, c.FieldOfList, c.MaxFieldOfList)
+		if err != nil {
+			return nil, err
 		}
-		result := chain.List()
-		if result.Error != nil {
-			return nil, fmt.Errorf("list failed: %w", result.Error)
+		rows, err := c.selectRows(params, fields, 0, 0)
+		if err != nil {
+			return nil, fmt.Errorf("list failed: %w", err)
 		}
-		return result.Data, nil
+		return rows, nil
 	}
 }
 
@@ -917,7 +2120,7 @@ func doNothingTransfer(input any) (any, error) {
 }
 
 // 使用示例
-func NewCrud(prefix, table string, db *gom.DB, transferMap map[string]string, fieldOfList []string, fieldOfDetail []string, handlerFilters []string) (*Crud, error) {
+func NewCrud(prefix, table string, db *gom.DB, transferMap map[string]string, fieldOfList []string, fieldOfDetail []string, handlerFilters []string, opts ...CrudOption) (*Crud, error) {
 	crud := &Crud{
 		Prefix:         prefix,
 		Table:          table,
@@ -926,7 +2129,27 @@ func NewCrud(prefix, table string, db *gom.DB, transferMap map[string]string, fi
 		FieldOfList:    fieldOfList,
 		FieldOfDetail:  fieldOfDetail,
 		handlerFilters: handlerFilters,
+		Dialect:        PostgresDialect,
 		queryBuilder:   NewQueryBuilder(db, table),
+
+		MaxFilterDepth:      DefaultMaxFilterDepth,
+		MaxFilterConditions: DefaultMaxFilterConditions,
+		SearchLanguage:      DefaultSearchLanguage,
+	}
+	for _, opt := range opts {
+		opt(crud)
+	}
+
+	switch crud.BatchMode {
+	case "", BatchModeAtomic, BatchModePartial:
+	default:
+		return nil, fmt.Errorf("unsupported batch mode: %s", crud.BatchMode)
+	}
+
+	switch crud.StrictFilters {
+	case "", StrictFiltersWrite, StrictFiltersAll, StrictFiltersNone:
+	default:
+		return nil, fmt.Errorf("unsupported strict filters mode: %s", crud.StrictFilters)
 	}
 
 	// Cache table column information
@@ -935,6 +2158,36 @@ func NewCrud(prefix, table string, db *gom.DB, transferMap map[string]string, fi
 		return nil, fmt.Errorf("failed to cache table info: %v", err)
 	}
 
+	if crud.VersionField != "" {
+		column, ok := crud.queryBuilder.columnCache[crud.VersionField]
+		if !ok {
+			return nil, fmt.Errorf("version field not found in table %s: %s", table, crud.VersionField)
+		}
+		if !isTimeField(column.DataType) && !isIntegerType(column.DataType) {
+			return nil, fmt.Errorf("version field %s must be an integer or time column", crud.VersionField)
+		}
+	}
+
+	if crud.SoftDeleteField != "" {
+		column, ok := crud.queryBuilder.columnCache[crud.SoftDeleteField]
+		if !ok {
+			return nil, fmt.Errorf("soft delete field not found in table %s: %s", table, crud.SoftDeleteField)
+		}
+		if !isTimeField(column.DataType) && column.DataType != "bool" {
+			return nil, fmt.Errorf("soft delete field %s must be a time or bool column", crud.SoftDeleteField)
+		}
+	}
+
+	if err := crud.checkSearchConfig(); err != nil {
+		return nil, err
+	}
+
+	if crud.IdentityField != "" {
+		if _, ok := crud.queryBuilder.columnCache[crud.IdentityField]; !ok {
+			return nil, fmt.Errorf("identity field not found in table %s: %s", table, crud.IdentityField)
+		}
+	}
+
 	if err := crud.InitDefaultHandler(); err != nil {
 		return nil, err
 	}
@@ -942,11 +2195,55 @@ func NewCrud(prefix, table string, db *gom.DB, transferMap map[string]string, fi
 }
 
 func RequestToQueryParamsTransfer(tableName string, transferMap map[string]string, columnMap map[string]define.ColumnInfo) ParseRequestFunc {
+	return requestToQueryParams(tableName, transferMap, columnMap, false)
+}
+
+// RequestToQueryParamsTransferStrict 与 RequestToQueryParamsTransfer 相同，但遇到未知列、无法解析的值或不支持的操作符时
+// 返回 InvalidParamsError，而不是忽略该参数
+func RequestToQueryParamsTransferStrict(tableName string, transferMap map[string]string, columnMap map[string]define.ColumnInfo) ParseRequestFunc {
+	return requestToQueryParams(tableName, transferMap, columnMap, true)
+}
+
+// 查询参数中的保留名称，不会被当作过滤条件
+var reservedQueryNames = map[string]bool{
+	"page":        true,
+	"pageSize":    true,
+	"orderBy":     true,
+	"orderByDesc": true,
+	"sort":        true,
+	"q":           true,
+	"expand":      true,
+	"rank":        true,
+	"fields":      true,
+	"cursor":      true,
+	"withDeleted": true,
+	"distinct":    true,
+	"force":       true,
+	"groupBy":     true,
+	"metrics":     true,
+	"bucket":      true,
+}
+
+func requestToQueryParams(tableName string, transferMap map[string]string, columnMap map[string]define.ColumnInfo, strict bool) ParseRequestFunc {
+	mapFields := func(v string) []string {
+		values := strings.Split(v, ",")
+		vv := make([]string, 0)
+		for _, vi := range values {
+			if vk, ok := transferMap[vi]; ok {
+				vv = append(vv, vk)
+			} else {
+				vv = append(vv, vi)
+			}
+		}
+		return vv
+	}
+
 	return func(c *fiber.Ctx) (any, error) {
 		fmt.Printf("RequestToQueryParamsTransfer: tableName=%s\n", tableName)
 		queryParams := QueryParams{
 			Table: tableName,
 		}
+		var invalid []InvalidParam
 
 		// 从Request的Query生成一个Map
 		c.Request().URI().QueryArgs().VisitAll(func(key, value []byte) {
@@ -956,6 +2253,7 @@ func RequestToQueryParamsTransfer(tableName string, transferMap map[string]strin
 			if k == "page" {
 				page, err := strconv.Atoi(v)
 				if err != nil {
+					invalid = append(invalid, InvalidParam{Name: k, Reason: "page must be an integer"})
 					return
 				}
 				if page < 1 {
@@ -965,6 +2263,7 @@ func RequestToQueryParamsTransfer(tableName string, transferMap map[string]strin
 			} else if k == "pageSize" {
 				pageSize, err := strconv.Atoi(v)
 				if err != nil {
+					invalid = append(invalid, InvalidParam{Name: k, Reason: "pageSize must be an integer"})
 					return
 				}
 				if pageSize < 1 {
@@ -972,55 +2271,101 @@ func RequestToQueryParamsTransfer(tableName string, transferMap map[string]strin
 				}
 				queryParams.PageSize = pageSize
 			} else if k == "orderBy" {
-				values := strings.Split(v, ",")
-				vv := make([]string, 0)
-				for _, vi := range values {
-					if vk, ok := transferMap[vi]; ok {
-						vv = append(vv, vk)
-					} else {
-						vv = append(vv, vi)
-					}
-				}
-				queryParams.OrderBy = vv
+				queryParams.OrderBy = mapFields(v)
 			} else if k == "orderByDesc" {
-				values := strings.Split(v, ",")
-				vv := make([]string, 0)
-				for _, vi := range values {
-					if vk, ok := transferMap[vi]; ok {
-						vv = append(vv, vk)
-					} else {
-						vv = append(vv, vi)
-					}
+				queryParams.OrderByDesc = mapFields(v)
+			} else if k == "sort" {
+				sorts, sortInvalid := parseSort(v, transferMap)
+				queryParams.Sort = append(queryParams.Sort, sorts...)
+				invalid = append(invalid, sortInvalid...)
+			} else if k == "fields" {
+				queryParams.Fields = mapFields(v)
+			} else if k == "cursor" {
+				queryParams.UseCursor = true
+				queryParams.Cursor = v
+			} else if k == "expand" {
+				queryParams.Expand = strings.Split(v, ",")
+			} else if k == "q" {
+				queryParams.Search = v
+			} else if k == "rank" {
+				queryParams.SearchRank, _ = strconv.ParseBool(v)
+			} else if k == "withDeleted" {
+				queryParams.WithDeleted, _ = strconv.ParseBool(v)
+			} else if k == "distinct" {
+				if vk, ok := transferMap[v]; ok {
+					v = vk
 				}
-				queryParams.OrderByDesc = vv
+				queryParams.Distinct = v
+			} else if reservedQueryNames[k] {
+				// 由具体操作自行解析的保留参数
+				return
 			} else {
 				// 从k中解析出key和op
-				key, op := KeyToKeyOp(k)
-				if newKey, ok := transferMap[key]; ok {
-					key = newKey
-				}
+				key, opName, op, known := parseFilterKey(k, transferMap, columnMap)
+				key, path := splitJSONField(key)
 				column, ok := columnMap[key]
-				if !ok {
+				if !ok || (path != nil && !isJSONColumn(column)) {
+					invalid = append(invalid, InvalidParam{Name: k, Reason: "unknown column"})
 					return
 				}
-				values := strings.Split(v, ",")
-				val, err := QueryValuesToValues(op, values, column)
+				if !known {
+					invalid = append(invalid, InvalidParam{Name: k, Reason: "unsupported operator " + opName})
+					return
+				}
+				var val any
+				var err error
+				if isJSONColumn(column) {
+					if op, err = jsonFilterOp(op, path); err == nil {
+						val, err = jsonFilterValue(op, v)
+					}
+				} else {
+					val, err = filterValue(op, v, column)
+				}
 				if err != nil {
+					invalid = append(invalid, InvalidParam{Name: k, Reason: err.Error()})
 					return
 				}
 				queryParams.ConditionParams = append(queryParams.ConditionParams, ConditionParam{
 					Key:    key,
+					Path:   path,
 					Op:     op,
 					Values: val,
 				})
 			}
 		})
 
+		if strict && len(invalid) > 0 {
+			return nil, &InvalidParamsError{Params: invalid}
+		}
+
 		fmt.Printf("RequestToQueryParamsTransfer: queryParams=%+v\n", queryParams)
 		return queryParams, nil
 	}
 }
 
+// filterValue 按操作符解析过滤参数值：空值判断忽略参数值，模糊匹配使用完整的字符串，其余按逗号拆分后转换为列类型
+func filterValue(op define.OpType, v string, column define.ColumnInfo) (any, error) {
+	switch op {
+	case define.OpIsNull, define.OpIsNotNull:
+		return nil, nil
+	case define.OpLike, define.OpNotLike, OpILike, OpStartsWith, OpEndsWith, OpContains:
+		if column.DataType != "string" {
+			return nil, fmt.Errorf("operator requires a string column, got %s", column.DataType)
+		}
+		return v, nil
+	}
+
+	values := strings.Split(v, ",")
+	if (op == define.OpBetween || op == define.OpNotBetween) && len(values) != 2 {
+		return nil, errors.New("between requires exactly two values")
+	}
+	val, err := QueryValuesToValues(op, values, column)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for %s: %w", column.DataType, err)
+	}
+	return val, nil
+}
+
 func QueryValuesToValues(op define.OpType, values []string, column define.ColumnInfo) (any, error) {
 	//将values转换为[]any
 	var err error
@@ -1070,6 +2415,14 @@ func TransferType(column define.ColumnInfo) TransferTypeFunc {
 			}
 			return val, nil
 		}
+	case "int64", "int":
+		return func(v string) (any, error) {
+			val, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			return val, nil
+		}
 	case "bool":
 		return func(v string) (any, error) {
 			val, err := strconv.ParseBool(v)
@@ -1183,48 +2536,121 @@ func TransferType(column define.ColumnInfo) TransferTypeFunc {
 	}
 }
 
+// 在 gom 操作符之外扩展的操作符：大小写不敏感匹配与 JSON 包含判断
+// startsWith、endsWith 与 contains 会转义参数值中的通配符，只做字面匹配
+// 取值从 100 开始，与 gom 自带的操作符错开
+const (
+	OpILike define.OpType = 100 + iota
+	OpStartsWith
+	OpEndsWith
+	OpContains
+	OpJSONContains // JSON 列的包含判断，对应 PostgreSQL 的 @> 与 MySQL 的 JSON_CONTAINS
+)
+
+// filterOps 过滤参数支持的操作符
+var filterOps = map[string]define.OpType{
+	"eq":         define.OpEq,
+	"ne":         define.OpNe,
+	"gt":         define.OpGt,
+	"ge":         define.OpGe,
+	"gte":        define.OpGe,
+	"lt":         define.OpLt,
+	"le":         define.OpLe,
+	"lte":        define.OpLe,
+	"in":         define.OpIn,
+	"notIn":      define.OpNotIn,
+	"isNull":     define.OpIsNull,
+	"isNotNull":  define.OpIsNotNull,
+	"between":    define.OpBetween,
+	"notBetween": define.OpNotBetween,
+	"like":       define.OpLike,
+	"notLike":    define.OpNotLike,
+	"ilike":      OpILike,
+	"startsWith": OpStartsWith,
+	"endsWith":   OpEndsWith,
+	"contains":   OpContains,
+}
+
 func KeyToKeyOp(key string) (string, define.OpType) {
+	field, op, _ := keyToKeyOp(key)
+	return field, op
+}
+
+// keyToKeyOp 与 KeyToKeyOp 相同，额外返回操作符后缀是否可以识别
+// 不知道表结构时，旧的 field_op 写法总是按最后一个下划线拆分
+func keyToKeyOp(key string) (string, define.OpType, bool) {
+	if field, opStr, explicit := splitFilterKey(key); explicit {
+		op, ok := filterOps[opStr]
+		return field, op, ok
+	}
+
 	lastIndex := strings.LastIndex(key, "_")
 	if lastIndex == -1 {
-		return key, define.OpEq
+		return key, define.OpEq, true
 	}
 
 	field := key[:lastIndex]
-	opStr := key[lastIndex+1:]
-	op := define.OpEq
-
-	switch opStr {
-	case "eq":
-		op = define.OpEq
-	case "ne":
-		op = define.OpNe
-	case "gt":
-		op = define.OpGt
-	case "ge":
-		op = define.OpGe
-	case "lt":
-		op = define.OpLt
-	case "le":
-		op = define.OpLe
-	case "in":
-		op = define.OpIn
-	case "notIn":
-		op = define.OpNotIn
-	case "isNull":
-		op = define.OpIsNull
-	case "isNotNull":
-		op = define.OpIsNotNull
-	case "between":
-		op = define.OpBetween
-	case "notBetween":
-		op = define.OpNotBetween
-	case "like":
-		op = define.OpLike
-	case "notLike":
-		op = define.OpNotLike
+	op, ok := filterOps[key[lastIndex+1:]]
+	if !ok {
+		return field, define.OpEq, false
 	}
+	return field, op, true
+}
 
-	return field, op
+// splitFilterKey 按显式语法 field[op] 或 field__op 拆分过滤参数名，explicit 表示是否使用了显式语法
+func splitFilterKey(key string) (field, op string, explicit bool) {
+	if strings.HasSuffix(key, "]") {
+		if i := strings.Index(key, "["); i > 0 {
+			return key[:i], key[i+1 : len(key)-1], true
+		}
+	}
+	if i := strings.LastIndex(key, "__"); i > 0 {
+		return key[:i], key[i+2:], true
+	}
+	return key, "", false
+}
+
+// parseFilterKey 解析过滤参数名，返回数据库列名、操作符名称与操作符
+// 支持 field[op]、field__op 以及旧的 field_op 写法。旧写法只有在后缀是已知操作符且前缀是真实列时才拆分，
+// 否则整个参数名按列名处理，例如 created_at 不会被拆成 created 与 at
+func parseFilterKey(key string, transferMap map[string]string, columnMap map[string]define.ColumnInfo) (string, string, define.OpType, bool) {
+	// JSON 列的路径写法 attrs.color 只映射列名部分
+	dbName := func(field string) string {
+		column, path := splitJSONField(field)
+		if newKey, ok := transferMap[column]; ok {
+			column = newKey
+		}
+		if path != nil {
+			return column + "." + strings.Join(path, ".")
+		}
+		return column
+	}
+	isField := func(field string) bool {
+		column, path := splitJSONField(field)
+		info, ok := columnMap[column]
+		return ok && (path == nil || isJSONColumn(info))
+	}
+
+	if field, opName, explicit := splitFilterKey(key); explicit {
+		op, ok := filterOps[opName]
+		return dbName(field), opName, op, ok
+	}
+
+	// JSON 路径中的键名可以任意取，带路径时优先按旧写法拆分，需要以操作符结尾的键名时使用显式语法
+	field := dbName(key)
+	if _, path := splitJSONField(field); path == nil && isField(field) {
+		return field, "eq", define.OpEq, true
+	}
+	if i := strings.LastIndex(key, "_"); i > 0 {
+		opName := key[i+1:]
+		if op, ok := filterOps[opName]; ok {
+			prefix := dbName(key[:i])
+			if isField(prefix) {
+				return prefix, opName, op, true
+			}
+		}
+	}
+	return field, "eq", define.OpEq, true
 }
 
 func (c *Crud) Handle(ctx *fiber.Ctx) error {
@@ -1280,6 +2706,15 @@ func (c *Crud) GetPrefix() string {
 	return c.Prefix
 }
 
+// isIntegerType 判断字段类型是否为整数类型
+func isIntegerType(dataType string) bool {
+	switch dataType {
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+		return true
+	}
+	return false
+}
+
 // isTimeField 判断字段类型是否为时间相关类型
 func isTimeField(dataType string) bool {
 	timeTypes := []string{
@@ -28,14 +28,34 @@ type DatabaseConfig struct {
 }
 
 type TableConfig struct {
-	Name           string            `yaml:"name"`
-	Database       string            `yaml:"database"`
-	Table          string            `yaml:"table"`
-	PathPrefix     string            `yaml:"path_prefix"`
-	TransferMap    map[string]string `yaml:"field_map"`
-	FieldOfList    []string          `yaml:"list_fields"`
-	FieldOfDetail  []string          `yaml:"detail_fields"`
-	HandlerFilters []string          `yaml:"handler_filters"`
+	Name                string            `yaml:"name"`
+	Database            string            `yaml:"database"`
+	Table               string            `yaml:"table"`
+	PathPrefix          string            `yaml:"path_prefix"`
+	TransferMap         map[string]string `yaml:"field_map"`
+	FieldOfList         []string          `yaml:"list_fields"`
+	FieldOfDetail       []string          `yaml:"detail_fields"`
+	HandlerFilters      []string          `yaml:"handler_filters"`
+	BatchMode           string            `yaml:"batch_mode"`            // 批量写入模式：atomic（默认）或 partial
+	ConflictFields      []string          `yaml:"conflict_fields"`       // upsert 判断冲突的列，默认为主键
+	UpsertFields        []string          `yaml:"upsert_fields"`         // upsert 冲突时覆盖的列，默认为请求中除冲突列以外的全部列
+	SoftDeleteField     string            `yaml:"soft_delete_field"`     // 软删除标记列（时间或布尔类型），为空时执行物理删除
+	VersionField        string            `yaml:"version_field"`         // 乐观锁版本列（整数或时间类型），为空时不做版本检查
+	AggregateFields     []string          `yaml:"aggregate_fields"`      // 允许聚合的数值列，为空时允许全部数值列
+	MaxFieldOfList      []string          `yaml:"max_list_fields"`       // 客户端通过 fields 参数最多可选择的列表字段，为空时只能选择 list_fields 中的字段
+	MaxFieldOfDetail    []string          `yaml:"max_detail_fields"`     // 客户端通过 fields 参数最多可选择的详情字段，为空时只能选择 detail_fields 中的字段
+	StrictFilters       string            `yaml:"strict_filters"`        // 查询参数严格校验模式：write（默认，仅写操作）、all 或 none
+	SortableFields      []string          `yaml:"sortable_fields"`       // 允许排序的列，为空时允许全部列
+	MaxFilterDepth      int               `yaml:"max_filter_depth"`      // query 操作中过滤条件的最大嵌套深度，默认 5
+	MaxFilterConditions int               `yaml:"max_filter_conditions"` // query 操作中过滤条件的最大条件数，默认 50
+	SearchFields        []string          `yaml:"search_fields"`         // q 参数搜索的列
+	SearchFullText      bool              `yaml:"search_fulltext"`       // 使用全文搜索：PostgreSQL 使用 tsvector，MySQL 使用 MATCH ... AGAINST（需要 FULLTEXT 索引）
+	SearchVector        string            `yaml:"search_vector"`         // PostgreSQL 预先计算的 tsvector 列，设置后自动启用全文搜索
+	SearchLanguage      string            `yaml:"search_language"`       // PostgreSQL 全文搜索配置，默认 simple
+	Relations           []RelationConfig  `yaml:"relations"`             // 可通过 expand 参数嵌入的关联
+	DiscoverRelations   bool              `yaml:"discover_relations"`    // 是否从数据库外键定义中发现关联
+	ReadOnly            bool              `yaml:"read_only"`             // 只读表或视图，只提供 get、list、page、count 与 table 操作
+	IdentityField       string            `yaml:"identity_field"`        // 唯一标识记录的列，用于没有主键的视图，设置后 get 必须按该列查询
 }
 
 // DBOptions 定义数据库初始化选项
@@ -47,26 +67,74 @@ type DBOptions struct {
 	Debug           bool  `yaml:"debug"`              // 是否开启调试模式
 }
 
+// RelationConfig 表之间的关联配置
+type RelationConfig struct {
+	Name         string `yaml:"name"`          // expand 参数中使用的名称
+	Table        string `yaml:"table"`         // 关联表，对应同一数据库中另一个表配置的 name 或表名
+	LocalField   string `yaml:"local_field"`   // 本表中的关联列
+	ForeignField string `yaml:"foreign_field"` // 关联表中的列，默认为关联表主键
+	Many         bool   `yaml:"many"`          // 一对多关联，嵌入列表
+	// 一对多关联在 update 中未出现的已有子记录的处理策略：keep（默认）、delete 或 error
+	MissingChildren string `yaml:"missing_children"`
+}
+
+// QueryConfig 以 GET 接口暴露的自定义 SQL 查询配置
+type QueryConfig struct {
+	Name       string             `yaml:"name"`
+	Database   string             `yaml:"database"`
+	PathPrefix string             `yaml:"path_prefix"` // 提供 {path_prefix}/list，开启分页时另外提供 {path_prefix}/page
+	SQL        string             `yaml:"sql"`         // SQL 模板，使用 :name 引用参数
+	Params     []QueryParamConfig `yaml:"params"`      // SQL 模板中的参数
+	Filters    []QueryParamConfig `yaml:"filters"`     // 允许按 _gt、_in 等方式过滤的结果列
+	Paging     bool               `yaml:"paging"`      // 是否提供分页接口
+	OrderBy    string             `yaml:"order_by"`    // 分页或过滤时结果的排序，如 "total DESC, id"
+}
+
+// QueryParamConfig 自定义查询的参数或过滤列
+type QueryParamConfig struct {
+	Name     string `yaml:"name"`
+	Type     string `yaml:"type"`     // string（默认）、int、float、bool 或 time
+	Required bool   `yaml:"required"` // 必须提供的参数，过滤列忽略该项
+	Default  string `yaml:"default"`  // 未提供参数时使用的默认值，没有默认值时绑定 NULL
+}
+
+// RoutineConfig 以 POST 接口暴露的数据库函数或存储过程配置
+type RoutineConfig struct {
+	Name       string             `yaml:"name"`        // 函数或存储过程名，可带 schema，如 billing.close_month
+	Database   string             `yaml:"database"`    // 例程所在的数据库
+	PathPrefix string             `yaml:"path_prefix"` // 提供 POST {path_prefix}/call
+	Params     []QueryParamConfig `yaml:"params"`      // 按调用顺序声明的参数
+	Result     string             `yaml:"result"`      // 返回结果形式：scalar、row 或 rows（默认）
+}
+
 type ServiceConfig struct {
 	Databases []DatabaseConfig `yaml:"databases"`
 	Tables    []TableConfig    `yaml:"tables"`
+	Queries   []QueryConfig    `yaml:"queries"`
+	Routines  []RoutineConfig  `yaml:"routines"`
 }
 
 // Basic type definitions to fix compilation errors
 
 // crud_manager.go
 type CrudManager struct {
-	config *ServiceConfig
-	dbs    map[string]*gom.DB
-	routes map[string]ICrud // key is full path for routing
-	mu     sync.RWMutex
+	config   *ServiceConfig
+	dbs      map[string]*gom.DB
+	dialects map[string]Dialect
+	routes   map[string]ICrud // key is full path for routing
+	queries  map[string]*NamedQuery
+	routines map[string]*Routine
+	mu       sync.RWMutex
 }
 
 func NewCrudManager(config *ServiceConfig) (*CrudManager, error) {
 	cm := &CrudManager{
-		config: config,
-		dbs:    make(map[string]*gom.DB),
-		routes: make(map[string]ICrud),
+		config:   config,
+		dbs:      make(map[string]*gom.DB),
+		dialects: make(map[string]Dialect),
+		routes:   make(map[string]ICrud),
+		queries:  make(map[string]*NamedQuery),
+		routines: make(map[string]*Routine),
 	}
 	return cm, nil
 }
@@ -78,6 +146,11 @@ func (cm *CrudManager) init() error {
 	for _, dbConf := range cm.config.Databases {
 		fmt.Printf("Connecting to database %s (%s)...\n", dbConf.Name, dbConf.Driver)
 
+		dialect, err := DialectOf(dbConf.Driver)
+		if err != nil {
+			return err
+		}
+
 		// 如果没有提供 DSN，则构建它
 		dsn := dbConf.DSN
 		if dsn == "" {
@@ -119,9 +192,11 @@ func (cm *CrudManager) init() error {
 		}
 		fmt.Printf("Successfully connected to database %s\n", dbConf.Name)
 		cm.dbs[dbConf.Name] = db
+		cm.dialects[dbConf.Name] = dialect
 	}
 
 	// 初始化表配置
+	cruds := make(map[string]*Crud, len(cm.config.Tables))
 	for _, tblConf := range cm.config.Tables {
 		fmt.Printf("Initializing table %s...\n", tblConf.Name)
 		db, ok := cm.dbs[tblConf.Database]
@@ -137,6 +212,24 @@ func (cm *CrudManager) init() error {
 			tableName = tblConf.Name // 如果表配置中没有指定 Table，则使用 Name
 		}
 
+		opts := []CrudOption{
+			WithDialect(cm.dialects[tblConf.Database]),
+			WithBatchMode(tblConf.BatchMode),
+			WithUpsert(tblConf.ConflictFields, tblConf.UpsertFields),
+			WithSoftDelete(tblConf.SoftDeleteField),
+			WithVersion(tblConf.VersionField),
+			WithAggregateFields(tblConf.AggregateFields),
+			WithMaxFields(tblConf.MaxFieldOfList, tblConf.MaxFieldOfDetail),
+			WithStrictFilters(tblConf.StrictFilters),
+			WithSortableFields(tblConf.SortableFields),
+			WithFilterLimits(tblConf.MaxFilterDepth, tblConf.MaxFilterConditions),
+			WithSearch(tblConf.SearchFields),
+			WithReadOnly(tblConf.ReadOnly, tblConf.IdentityField),
+		}
+		if tblConf.SearchFullText || tblConf.SearchVector != "" {
+			opts = append(opts, WithFullTextSearch(tblConf.SearchVector, tblConf.SearchLanguage))
+		}
+
 		crud, err := NewCrud(
 			tblConf.PathPrefix,
 			tableName,
@@ -145,21 +238,115 @@ func (cm *CrudManager) init() error {
 			tblConf.FieldOfList,
 			tblConf.FieldOfDetail,
 			tblConf.HandlerFilters,
+			opts...,
 		)
 		if err != nil {
 			return fmt.Errorf("failed to create crud for %s: %v", tblConf.Name, err)
 		}
 
 		cm.routes[tblConf.PathPrefix] = crud
+		cruds[tblConf.Name] = crud
 		fmt.Printf("Registered CRUD instance for table %s\n", tblConf.Name)
 	}
 
+	if err := cm.initRelations(cruds); err != nil {
+		return err
+	}
+
+	// 初始化自定义查询
+	for _, queryConf := range cm.config.Queries {
+		db, ok := cm.dbs[queryConf.Database]
+		if !ok {
+			return fmt.Errorf("database not found for query %s: %s", queryConf.Name, queryConf.Database)
+		}
+		if _, exists := cm.routes[queryConf.PathPrefix]; exists {
+			return fmt.Errorf("query %s: path prefix already used by a table: %s", queryConf.Name, queryConf.PathPrefix)
+		}
+		if _, exists := cm.queries[queryConf.PathPrefix]; exists {
+			return fmt.Errorf("query %s: path prefix already used by another query: %s", queryConf.Name, queryConf.PathPrefix)
+		}
+		query, err := NewNamedQuery(queryConf, db, cm.dialects[queryConf.Database])
+		if err != nil {
+			return err
+		}
+		cm.queries[queryConf.PathPrefix] = query
+	}
+
+	// 初始化函数与存储过程
+	for _, routineConf := range cm.config.Routines {
+		db, ok := cm.dbs[routineConf.Database]
+		if !ok {
+			return fmt.Errorf("database not found for routine %s: %s", routineConf.Name, routineConf.Database)
+		}
+		_, usedByTable := cm.routes[routineConf.PathPrefix]
+		_, usedByQuery := cm.queries[routineConf.PathPrefix]
+		_, usedByRoutine := cm.routines[routineConf.PathPrefix]
+		if usedByTable || usedByQuery || usedByRoutine {
+			return fmt.Errorf("routine %s: path prefix already used: %s", routineConf.Name, routineConf.PathPrefix)
+		}
+		routine, err := NewRoutine(routineConf, db, cm.dialects[routineConf.Database])
+		if err != nil {
+			return err
+		}
+		cm.routines[routineConf.PathPrefix] = routine
+	}
+
 	fmt.Println("CrudManager initialization completed.")
 	return nil
 }
 
+// initRelations 在全部表创建后解析声明的关联，并按需从外键定义中发现关联，关联表必须与本表在同一个数据库中
+func (cm *CrudManager) initRelations(cruds map[string]*Crud) error {
+	for _, tblConf := range cm.config.Tables {
+		if len(tblConf.Relations) == 0 && !tblConf.DiscoverRelations {
+			continue
+		}
+		crud := cruds[tblConf.Name]
+
+		// 同一数据库中按表名与配置名称索引的 Crud
+		byTable := make(map[string]*Crud)
+		byName := make(map[string]*Crud)
+		for _, other := range cm.config.Tables {
+			if other.Database == tblConf.Database {
+				byTable[cruds[other.Name].Table] = cruds[other.Name]
+				byName[other.Name] = cruds[other.Name]
+			}
+		}
+
+		for _, relConf := range tblConf.Relations {
+			target, ok := byName[relConf.Table]
+			if !ok {
+				target, ok = byTable[relConf.Table]
+			}
+			if !ok {
+				return fmt.Errorf("relation %s of table %s: table not found in database %s: %s",
+					relConf.Name, tblConf.Name, tblConf.Database, relConf.Table)
+			}
+			if err := crud.AddRelation(Relation{
+				Name:            relConf.Name,
+				Target:          target,
+				LocalField:      relConf.LocalField,
+				ForeignField:    relConf.ForeignField,
+				Many:            relConf.Many,
+				MissingChildren: relConf.MissingChildren,
+			}); err != nil {
+				return err
+			}
+		}
+
+		if tblConf.DiscoverRelations {
+			if err := crud.DiscoverRelations(byTable); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // RegisterRoutes 注册统一路由
 func (cm *CrudManager) RegisterRoutes(r fiber.Router) {
+	// 事务批量接口
+	r.Post("/"+PathBatch, cm.handleBatch)
 	// 注册所有路由
 	r.All("/*", cm.handle)
 }
@@ -184,8 +371,17 @@ func (cm *CrudManager) handle(c *fiber.Ctx) error {
 	if crud, exists := cm.routes["/"+prefix]; exists {
 		matchedCrud = crud
 	}
+	query := cm.queries["/"+prefix]
+	routine := cm.routines["/"+prefix]
 	cm.mu.RUnlock()
 
+	if matchedCrud == nil && query != nil {
+		return query.Handle(c, path[lastSlashIndex+1:])
+	}
+	if matchedCrud == nil && routine != nil {
+		return routine.Handle(c, path[lastSlashIndex+1:])
+	}
+
 	if matchedCrud == nil {
 		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "path not configured"})
 	}
@@ -206,7 +402,10 @@ func (cm *CrudManager) UpdateConfig(newConf *ServiceConfig) error {
 	// 应用新配置
 	cm.config = newConf
 	cm.dbs = make(map[string]*gom.DB)
+	cm.dialects = make(map[string]Dialect)
 	cm.routes = make(map[string]ICrud)
+	cm.queries = make(map[string]*NamedQuery)
+	cm.routines = make(map[string]*Routine)
 	return cm.init()
 }
 
@@ -0,0 +1,354 @@
+package crudo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/kmlixh/gom/v4"
+	"github.com/kmlixh/gom/v4/define"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyCondition(t *testing.T) {
+	c := &Crud{Dialect: PostgresDialect}
+
+	cond, values, err := c.keyCondition([]string{"id"}, []any{1, map[string]any{"id": 2}}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, `"id" IN ($1, $2)`, cond)
+	assert.Equal(t, []any{1, 2}, values)
+
+	keys := []string{"order_id", "product_id"}
+	cond, values, err = c.keyCondition(keys, []any{
+		map[string]any{"order_id": 1, "product_id": 2},
+		map[string]any{"order_id": 1, "product_id": 3},
+	}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, `("order_id" = $1 AND "product_id" = $2) OR ("order_id" = $3 AND "product_id" = $4)`, cond)
+	assert.Equal(t, []any{1, 2, 1, 3}, values)
+
+	_, _, err = c.keyCondition(keys, []any{1}, 1)
+	assert.Error(t, err)
+	_, _, err = c.keyCondition(keys, []any{map[string]any{"order_id": 1}}, 1)
+	assert.Error(t, err)
+}
+
+func TestCheckInsertKeys(t *testing.T) {
+	tableInfo := &define.TableInfo{
+		PrimaryKeys: []string{"id"},
+		Columns:     []define.ColumnInfo{{Name: "id", IsAutoIncrement: true}},
+	}
+	data := map[string]any{"id": 0, "name": "a"}
+	assert.NoError(t, checkInsertKeys(tableInfo, data))
+	assert.NotContains(t, data, "id")
+	assert.Error(t, checkInsertKeys(tableInfo, map[string]any{"id": 5}))
+
+	tableInfo = &define.TableInfo{
+		PrimaryKeys: []string{"order_id", "product_id"},
+		Columns:     []define.ColumnInfo{{Name: "order_id"}, {Name: "product_id"}},
+	}
+	assert.NoError(t, checkInsertKeys(tableInfo, map[string]any{"order_id": 1, "product_id": 2}))
+	assert.Error(t, checkInsertKeys(tableInfo, map[string]any{"order_id": 1}))
+}
+
+func TestUpsertUpdateFields(t *testing.T) {
+	c := &Crud{}
+	data := map[string]any{"sku": "A1", "price": 10, "stock": 3}
+	assert.Equal(t, []string{"price", "stock"}, c.upsertUpdateFields(data, []string{"sku"}))
+
+	// 只覆盖 UpsertFields 中请求提供的列
+	c.UpsertFields = []string{"stock", "updated_at", "name"}
+	assert.Equal(t, []string{"stock"}, c.upsertUpdateFields(data, []string{"sku"}))
+	assert.Empty(t, c.upsertUpdateFields(map[string]any{"sku": "A1"}, []string{"sku"}))
+}
+
+func TestCoerceValue(t *testing.T) {
+	intCol := define.ColumnInfo{Name: "age", DataType: "int64"}
+	v, err := coerceValue(intCol, float64(18))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(18), v)
+	_, err = coerceValue(intCol, 1.5)
+	assert.Error(t, err)
+	_, err = coerceValue(intCol, nil)
+	assert.Error(t, err)
+
+	nullableCol := define.ColumnInfo{Name: "nickname", DataType: "string", IsNullable: true}
+	v, err = coerceValue(nullableCol, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+
+	boolCol := define.ColumnInfo{Name: "enabled", DataType: "bool"}
+	v, err = coerceValue(boolCol, "true")
+	assert.NoError(t, err)
+	assert.Equal(t, true, v)
+}
+
+func TestBuildSetClause(t *testing.T) {
+	sets, values, err := buildSetClause(PostgresDialect, map[string]any{"name": "a", "age": nil}, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, `"age" = $2, "name" = $3`, sets)
+	assert.Equal(t, []any{nil, "a"}, values)
+}
+
+func TestSoftDeleteCondition(t *testing.T) {
+	c := &Crud{
+		Dialect:         PostgresDialect,
+		SoftDeleteField: "deleted_at",
+		queryBuilder: &QueryBuilder{columnCache: map[string]define.ColumnInfo{
+			"deleted_at": {Name: "deleted_at", DataType: "time.Time", IsNullable: true},
+			"is_deleted": {Name: "is_deleted", DataType: "bool"},
+		}},
+	}
+	assert.Equal(t, `"deleted_at" IS NULL`, c.notDeletedCondition())
+	_, restored := c.softDeleteValues()
+	assert.Nil(t, restored)
+
+	c.SoftDeleteField = "is_deleted"
+	assert.Equal(t, `"is_deleted" IS NOT TRUE`, c.notDeletedCondition())
+	deleted, restored := c.softDeleteValues()
+	assert.Equal(t, true, deleted)
+	assert.Equal(t, false, restored)
+}
+
+func TestVersionIncrement(t *testing.T) {
+	c := &Crud{
+		Table:   "orders",
+		Dialect: PostgresDialect,
+		queryBuilder: &QueryBuilder{columnCache: map[string]define.ColumnInfo{
+			"version":    {Name: "version", DataType: "int64"},
+			"updated_at": {Name: "updated_at", DataType: "time.Time"},
+		}},
+	}
+	assert.Equal(t, "", c.versionIncrement())
+	c.VersionField = "version"
+	assert.Equal(t, `"version" = "orders"."version" + 1`, c.versionIncrement())
+	c.VersionField = "updated_at"
+	assert.Equal(t, "", c.versionIncrement())
+}
+
+func TestRestoreRequiresCondition(t *testing.T) {
+	c := &Crud{Table: "orders", SoftDeleteField: "deleted_at"}
+	restore := c.restoreOperation()
+	for _, input := range []any{RestoreRequest{}, QueryParams{}} {
+		_, err := restore(input)
+		assert.ErrorContains(t, err, "invalid request body", "%T", input)
+	}
+}
+
+func TestDeleteRequiresCondition(t *testing.T) {
+	// 软删除与物理删除都不允许在没有 ids 与条件时删除全表
+	for _, c := range []*Crud{{Table: "orders", SoftDeleteField: "deleted_at"}, {Table: "orders"}} {
+		for _, input := range []any{DeleteWhereRequest{}, QueryParams{}} {
+			_, err := c.deleteRecords(nil, input)
+			assert.ErrorContains(t, err, "set force=true to delete all rows", "%T", input)
+		}
+	}
+}
+
+func TestRenderErrsVersionConflict(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(ctx *fiber.Ctx) error {
+		return RenderErrs(ctx, fmt.Errorf("%w: expected version 1, current 2", ErrVersionConflict))
+	})
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.NoError(t, err)
+	var body CodeMsg
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, http.StatusConflict, body.Code)
+}
+
+func TestToInt64(t *testing.T) {
+	for _, v := range []any{int64(3), 3, int32(3), float64(3), []byte("3"), "3"} {
+		n, err := toInt64(v)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), n)
+	}
+	_, err := toInt64(struct{}{})
+	assert.Error(t, err)
+}
+
+func TestResolveFields(t *testing.T) {
+	c := &Crud{
+		Dialect:     PostgresDialect,
+		TransferMap: map[string]string{"name": "product_name"},
+		queryBuilder: &QueryBuilder{columnCache: map[string]define.ColumnInfo{
+			"id":           {Name: "id"},
+			"product_name": {Name: "product_name"},
+			"secret":       {Name: "secret"},
+		}},
+	}
+	defaults := []string{"id"}
+	allowed := []string{"id", "product_name"}
+
+	fields, err := c.resolveFields(nil, defaults, allowed)
+	assert.NoError(t, err)
+	assert.Equal(t, defaults, fields)
+
+	fields, err = c.resolveFields([]string{"product_name", "secret"}, defaults, allowed)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"product_name"}, fields)
+
+	_, err = c.resolveFields([]string{"secret"}, defaults, allowed)
+	assert.Error(t, err)
+	_, err = c.resolveFields([]string{"missing"}, defaults, nil)
+	assert.Error(t, err)
+
+	// 未配置最大字段集合时只能选择默认字段，默认字段也为空时允许全部列
+	fields, err = c.resolveFields([]string{"id", "secret"}, defaults, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id"}, fields)
+	_, err = c.resolveFields([]string{"secret"}, defaults, nil)
+	assert.Error(t, err)
+	fields, err = c.resolveFields([]string{"secret"}, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"secret"}, fields)
+}
+
+func TestRequestToQueryParamsStrict(t *testing.T) {
+	columns := map[string]define.ColumnInfo{
+		"status": {Name: "status", DataType: "int32"},
+		"name":   {Name: "name", DataType: "string"},
+	}
+
+	var parsed any
+	var parseErr error
+	app := fiber.New()
+	app.Get("/strict", func(ctx *fiber.Ctx) error {
+		parsed, parseErr = RequestToQueryParamsTransferStrict("t", nil, columns)(ctx)
+		return RenderErrs(ctx, parseErr)
+	})
+	app.Get("/lenient", func(ctx *fiber.Ctx) error {
+		parsed, parseErr = RequestToQueryParamsTransfer("t", nil, columns)(ctx)
+		return RenderErrs(ctx, parseErr)
+	})
+
+	_, err := app.Test(httptest.NewRequest(http.MethodGet, "/strict?stauts_eq=1&status_eq=x&name_foo=a&page=2&orderBy=name", nil))
+	assert.NoError(t, err)
+	var paramsErr *InvalidParamsError
+	assert.ErrorAs(t, parseErr, &paramsErr)
+	names := make([]string, len(paramsErr.Params))
+	for i, p := range paramsErr.Params {
+		names[i] = p.Name
+	}
+	assert.ElementsMatch(t, []string{"stauts_eq", "status_eq", "name_foo"}, names)
+
+	_, err = app.Test(httptest.NewRequest(http.MethodGet, "/lenient?stauts_eq=1&status_eq=3", nil))
+	assert.NoError(t, err)
+	assert.NoError(t, parseErr)
+	assert.Len(t, parsed.(QueryParams).ConditionParams, 1)
+}
+
+func TestParseFilterKey(t *testing.T) {
+	columns := map[string]define.ColumnInfo{
+		"created_at": {Name: "created_at"},
+		"status":     {Name: "status"},
+		"user_in":    {Name: "user_in"},
+	}
+	transfer := map[string]string{"createdAt": "created_at"}
+
+	cases := []struct {
+		key   string
+		field string
+		op    define.OpType
+		known bool
+	}{
+		{"created_at", "created_at", define.OpEq, true},
+		{"created_at[gte]", "created_at", define.OpGe, true},
+		{"created_at__lt", "created_at", define.OpLt, true},
+		{"createdAt[le]", "created_at", define.OpLe, true},
+		{"status_in", "status", define.OpIn, true},
+		{"user_in", "user_in", define.OpEq, true},
+		{"user_in_ne", "user_in", define.OpNe, true},
+		{"created_foo", "created_foo", define.OpEq, true},
+		{"status[foo]", "status", define.OpEq, false},
+	}
+	for _, tc := range cases {
+		field, _, op, known := parseFilterKey(tc.key, transfer, columns)
+		assert.Equal(t, tc.field, field, tc.key)
+		assert.Equal(t, tc.known, known, tc.key)
+		if tc.known {
+			assert.Equal(t, tc.op, op, tc.key)
+		}
+	}
+}
+
+func TestSaveBatch(t *testing.T) {
+	records := []map[string]any{{"name": "a"}, {"id": 5, "name": "b"}, {"name": "c"}}
+	insert := func(chain *gom.Chain, data map[string]any) (map[string]any, error) {
+		if _, ok := data["id"]; ok {
+			return nil, errors.New("auto increment primary key id must not be set")
+		}
+		return map[string]any{"name": data["name"]}, nil
+	}
+	var rolledBack bool
+	transaction := func(fn func(tx *gom.Chain) error) error {
+		err := fn(&gom.Chain{})
+		rolledBack = err != nil
+		return err
+	}
+
+	// atomic 模式任一行失败时整体回滚
+	c := &Crud{}
+	_, err := c.saveBatch(records, insert, transaction)
+	assert.ErrorContains(t, err, "batch save failed at row 1")
+	assert.True(t, rolledBack)
+
+	result, err := c.saveBatch(records[:1], insert, transaction)
+	assert.NoError(t, err)
+	assert.False(t, rolledBack)
+	assert.Equal(t, 1, result["success_count"])
+
+	// partial 模式逐行返回结果，不使用事务
+	c.BatchMode = BatchModePartial
+	result, err = c.saveBatch(records, insert, func(func(tx *gom.Chain) error) error {
+		t.Fatal("partial mode must not open a transaction")
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result["success_count"])
+	assert.Equal(t, 1, result["failed_count"])
+	results := result["results"].([]BatchRowResult)
+	assert.Equal(t, map[string]any{"name": "a"}, results[0].Data)
+	assert.Equal(t, 1, results[1].Index)
+	assert.Contains(t, results[1].Error, "must not be set")
+	assert.Equal(t, map[string]any{"name": "c"}, results[2].Data)
+}
+
+func TestRequestToMapList(t *testing.T) {
+	c := &Crud{TransferMap: map[string]string{"productName": "product_name"}}
+
+	var parsed any
+	var parseErr error
+	app := fiber.New()
+	app.Post("/", func(ctx *fiber.Ctx) error {
+		parsed, parseErr = c.requestToMapList()(ctx)
+		return nil
+	})
+	post := func(body string) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		_, err := app.Test(req)
+		assert.NoError(t, err)
+	}
+
+	post(`[{"productName": "a"}, {"productName": "b"}]`)
+	assert.NoError(t, parseErr)
+	assert.Len(t, parsed, 2)
+	assert.Equal(t, "b", parsed.([]map[string]any)[1]["product_name"])
+
+	for _, body := range []string{`[]`, `{"productName": "a"}`} {
+		post(body)
+		assert.ErrorContains(t, parseErr, "invalid request body", body)
+	}
+}
+
+func TestNewCrudBatchMode(t *testing.T) {
+	_, err := NewCrud("/orders", "orders", nil, nil, nil, nil, nil, WithBatchMode("bulk"))
+	assert.ErrorContains(t, err, "unsupported batch mode")
+}
@@ -0,0 +1,231 @@
+package crudo
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// CursorPage 游标分页的结果
+type CursorPage struct {
+	List       []map[string]any `json:"list"`
+	PageSize   int              `json:"pageSize"`
+	HasNext    bool             `json:"hasNext"`
+	NextCursor string           `json:"nextCursor"`
+}
+
+// sortKey 排序列及方向
+type sortKey struct {
+	Field string
+	Desc  bool
+}
+
+// sortKeys 返回游标分页使用的排序列：orderBy、orderByDesc、sort 中的排序项，最后以主键或标识列补齐保证顺序唯一
+func (c *Crud) sortKeys(params QueryParams) ([]sortKey, error) {
+	identity, err := c.identityKeys()
+	if err != nil {
+		return nil, err
+	}
+	if len(identity) == 0 {
+		return nil, errors.New("cursor pagination requires a primary key or an identity field")
+	}
+
+	sorts, err := c.sortFields(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []sortKey
+	seen := make(map[string]bool)
+	var invalid []InvalidParam
+	for _, sort := range sorts {
+		if sort.Nulls != "" {
+			return nil, errors.New("invalid request body: nulls ordering is not supported with cursor pagination")
+		}
+		// keyset 条件中的比较遇到 NULL 时结果为 NULL，会漏掉或重复返回记录，因此不允许按可空列排序
+		if c.queryBuilder.columnCache[sort.Field].IsNullable {
+			invalid = append(invalid, InvalidParam{Name: c.apiFieldName(sort.Field), Reason: "nullable column cannot be sorted with cursor pagination"})
+			continue
+		}
+		if !seen[sort.Field] {
+			seen[sort.Field] = true
+			keys = append(keys, sortKey{Field: sort.Field, Desc: sort.Desc})
+		}
+	}
+	if len(invalid) > 0 {
+		return nil, &InvalidParamsError{Params: invalid}
+	}
+	// 以主键或标识列补齐，保证顺序唯一
+	for _, field := range identity {
+		if !seen[field] {
+			seen[field] = true
+			keys = append(keys, sortKey{Field: field})
+		}
+	}
+	return keys, nil
+}
+
+// encodeCursor 将最后一行的排序列值编码为不透明的游标
+func encodeCursor(keys []sortKey, row map[string]any) (string, error) {
+	values := make([]any, len(keys))
+	for i, key := range keys {
+		values[i] = row[key.Field]
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor 解析游标，并按列类型还原排序列的值
+func (c *Crud) decodeCursor(keys []sortKey, cursor string) ([]any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request body: malformed cursor: %w", err)
+	}
+	var values []any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&values); err != nil {
+		return nil, fmt.Errorf("invalid request body: malformed cursor: %w", err)
+	}
+	if len(values) != len(keys) {
+		return nil, errors.New("invalid request body: cursor does not match the requested order")
+	}
+
+	for i, key := range keys {
+		column := c.queryBuilder.columnCache[key.Field]
+		column.IsNullable = true
+		if values[i], err = coerceValue(column, values[i]); err != nil {
+			return nil, fmt.Errorf("invalid request body: malformed cursor: %w", err)
+		}
+	}
+	return values, nil
+}
+
+// keysetCondition 构建取游标之后记录的条件，如 (a > ?) OR (a = ? AND b < ?)
+func keysetCondition(d Dialect, keys []sortKey, values []any, startIndex int) (string, []any) {
+	var groups []string
+	var args []any
+	for i, key := range keys {
+		parts := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			parts = append(parts, fmt.Sprintf("%s = %s", d.Quote(keys[j].Field), d.Placeholder(startIndex+len(args))))
+			args = append(args, values[j])
+		}
+		op := ">"
+		if key.Desc {
+			op = "<"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s %s", d.Quote(key.Field), op, d.Placeholder(startIndex+len(args))))
+		args = append(args, values[i])
+		groups = append(groups, "("+strings.Join(parts, " AND ")+")")
+	}
+	return "(" + strings.Join(groups, " OR ") + ")", args
+}
+
+// cursorPage 按游标分页查询，每次多取一行用于判断是否还有下一页
+func (c *Crud) cursorPage(params QueryParams) (any, error) {
+	if params.SearchRank {
+		return nil, errors.New("invalid request body: rank is not supported with cursor pagination")
+	}
+	keys, err := c.sortKeys(params)
+	if err != nil {
+		return nil, err
+	}
+
+	pageSize := params.PageSize
+	if pageSize == 0 {
+		pageSize = 10
+	}
+
+	where, values, err := c.whereClause(params, 1)
+	if err != nil {
+		return nil, err
+	}
+	conditions := make([]string, 0, 2)
+	if where != "" {
+		conditions = append(conditions, where)
+	}
+	if params.Cursor != "" {
+		cursorValues, err := c.decodeCursor(keys, params.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		condition, args := keysetCondition(c.Dialect, keys, cursorValues, len(values)+1)
+		conditions = append(conditions, condition)
+		values = append(values, args...)
+	}
+
+	// 指定了返回字段时，额外查询排序列用于生成游标，返回前再移除
+	listFields, err := c.resolveFields(params.Fields, c.FieldOfList, c.MaxFieldOfList)
+	if err != nil {
+		return nil, err
+	}
+	relations, err := c.expandRelations(params.Expand)
+	if err != nil {
+		return nil, err
+	}
+	fields := "*"
+	extra := make(map[string]bool)
+	if len(listFields) > 0 {
+		var expandExtra []string
+		listFields, expandExtra = expandFields(listFields, relations)
+		for _, field := range expandExtra {
+			extra[field] = true
+		}
+		selected := make([]string, 0, len(listFields)+len(keys))
+		for _, field := range listFields {
+			selected = append(selected, c.Dialect.Quote(field))
+		}
+		for _, key := range keys {
+			if !contains(listFields, key.Field) {
+				extra[key.Field] = true
+				selected = append(selected, c.Dialect.Quote(key.Field))
+			}
+		}
+		fields = strings.Join(selected, ", ")
+	}
+
+	orders := make([]string, len(keys))
+	for i, key := range keys {
+		orders[i] = c.Dialect.Quote(key.Field)
+		if key.Desc {
+			orders[i] += " DESC"
+		}
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s", fields, c.Dialect.Quote(c.Table))
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY %s LIMIT %d", strings.Join(orders, ", "), pageSize+1)
+
+	rows, err := queryRows(c.Db.Chain(), query, values...)
+	if err != nil {
+		return nil, fmt.Errorf("list failed: %w", err)
+	}
+	c.decodeJSONColumns(rows...)
+
+	page := &CursorPage{PageSize: pageSize, List: rows}
+	if len(rows) > pageSize {
+		page.HasNext = true
+		page.List = rows[:pageSize]
+		if page.NextCursor, err = encodeCursor(keys, page.List[pageSize-1]); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.expandRows(page.List, relations); err != nil {
+		return nil, err
+	}
+	for _, row := range page.List {
+		for field := range extra {
+			delete(row, field)
+		}
+	}
+	return page, nil
+}
@@ -0,0 +1,59 @@
+package crudo
+
+import (
+	"testing"
+
+	"github.com/kmlixh/gom/v4/define"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeysetCondition(t *testing.T) {
+	keys := []sortKey{{Field: "created_at", Desc: true}, {Field: "id"}}
+	cond, args := keysetCondition(PostgresDialect, keys, []any{"2025-01-01", 7}, 2)
+	assert.Equal(t, `(("created_at" < $2) OR ("created_at" = $3 AND "id" > $4))`, cond)
+	assert.Equal(t, []any{"2025-01-01", "2025-01-01", 7}, args)
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	c := &Crud{
+		Dialect: PostgresDialect,
+		queryBuilder: &QueryBuilder{columnCache: map[string]define.ColumnInfo{
+			"id":   {Name: "id", DataType: "int64"},
+			"name": {Name: "name", DataType: "string"},
+		}},
+	}
+	keys := []sortKey{{Field: "name"}, {Field: "id"}}
+
+	cursor, err := encodeCursor(keys, map[string]any{"id": int64(9007199254740993), "name": "bob"})
+	assert.NoError(t, err)
+
+	values, err := c.decodeCursor(keys, cursor)
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"bob", int64(9007199254740993)}, values)
+
+	_, err = c.decodeCursor(keys[:1], cursor)
+	assert.Error(t, err)
+	_, err = c.decodeCursor(keys, "not a cursor")
+	assert.Error(t, err)
+}
+
+func TestSortKeysNullable(t *testing.T) {
+	c := &Crud{
+		Dialect:       PostgresDialect,
+		IdentityField: "id",
+		queryBuilder: &QueryBuilder{columnCache: map[string]define.ColumnInfo{
+			"id":         {Name: "id", DataType: "int64"},
+			"name":       {Name: "name", DataType: "string"},
+			"deleted_at": {Name: "deleted_at", DataType: "time.Time", IsNullable: true},
+		}},
+	}
+
+	keys, err := c.sortKeys(QueryParams{OrderByDesc: []string{"name"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []sortKey{{Field: "name", Desc: true}, {Field: "id"}}, keys)
+
+	_, err = c.sortKeys(QueryParams{OrderBy: []string{"deleted_at"}})
+	var paramsErr *InvalidParamsError
+	assert.ErrorAs(t, err, &paramsErr)
+	assert.Equal(t, "deleted_at", paramsErr.Params[0].Name)
+}
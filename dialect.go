@@ -0,0 +1,446 @@
+package crudo
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kmlixh/gom/v4"
+)
+
+// Dialect 封装不同数据库之间的 SQL 语法差异
+type Dialect interface {
+	// Name 返回方言名称，与 DatabaseConfig.Driver 保持一致
+	Name() string
+	// Placeholder 返回第 index 个参数的占位符，index 从 1 开始
+	Placeholder(index int) string
+	// Quote 为表名、列名等标识符加上引号
+	Quote(identifier string) string
+	// SupportsReturning 是否支持 INSERT ... RETURNING 直接取回插入的行
+	SupportsReturning() bool
+	// Upsert 返回追加在 INSERT 语句之后的冲突处理子句，updateColumns 为空时保留已有数据
+	// extraSets 为追加在更新列之后的 SET 项，如版本列自增
+	Upsert(conflictColumns, updateColumns, extraSets []string) string
+	// DateTrunc 返回将时间列截断到 hour/day/week/month 的表达式，column 需已加引号
+	DateTrunc(unit, column string) string
+	// OrderBy 返回单个排序项，nulls 为 NullsFirst、NullsLast 或空，column 需已加引号
+	OrderBy(column string, desc bool, nulls string) string
+	// ILike 返回大小写不敏感的 LIKE 条件，column 需已加引号
+	ILike(column, placeholder string) string
+	// FullTextSearch 返回全文搜索的匹配条件与相关度表达式，columns 与 vector 需已加引号，vector 为空时按 columns 计算
+	FullTextSearch(columns []string, vector, language, placeholder string) (match, rank string)
+	// JSONExtract 返回提取 JSON 路径的表达式，numeric 为 true 时按数值提取，否则按文本提取
+	JSONExtract(column string, path []string, numeric bool) string
+	// JSONContains 返回 JSON 列包含参数文档的条件
+	JSONContains(column, placeholder string) string
+	// JSONMergePatch 返回按 RFC 7396 将 patch 合并到列中已有文档的表达式与参数，占位符从 startIndex 开始
+	JSONMergePatch(column string, patch map[string]any, startIndex int) (string, []any, error)
+	// ForeignKeysQuery 返回查询表外键的语句，唯一的参数为表名
+	// 结果列为 constraint_name、column_name、ref_table、ref_column
+	ForeignKeysQuery() string
+	// CallRoutine 返回调用函数或存储过程的语句，name 需已加引号，参数占位符从 1 开始
+	// scalar 为 true 时返回值位于 result 列，否则返回例程产生的结果集
+	CallRoutine(name string, args int, scalar bool) string
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string { return "postgres" }
+
+func (postgresDialect) Placeholder(index int) string { return fmt.Sprintf("$%d", index) }
+
+func (postgresDialect) Quote(identifier string) string {
+	return `"` + strings.ReplaceAll(identifier, `"`, `""`) + `"`
+}
+
+func (postgresDialect) SupportsReturning() bool { return true }
+
+func (d postgresDialect) Upsert(conflictColumns, updateColumns, extraSets []string) string {
+	conflict := make([]string, len(conflictColumns))
+	for i, col := range conflictColumns {
+		conflict[i] = d.Quote(col)
+	}
+	if len(updateColumns) == 0 && len(extraSets) == 0 {
+		return fmt.Sprintf("ON CONFLICT (%s) DO NOTHING", strings.Join(conflict, ", "))
+	}
+	sets := make([]string, len(updateColumns), len(updateColumns)+len(extraSets))
+	for i, col := range updateColumns {
+		sets[i] = fmt.Sprintf("%s = EXCLUDED.%s", d.Quote(col), d.Quote(col))
+	}
+	sets = append(sets, extraSets...)
+	return fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(conflict, ", "), strings.Join(sets, ", "))
+}
+
+func (postgresDialect) DateTrunc(unit, column string) string {
+	return fmt.Sprintf("date_trunc('%s', %s)", unit, column)
+}
+
+func (postgresDialect) OrderBy(column string, desc bool, nulls string) string {
+	order := column
+	if desc {
+		order += " DESC"
+	}
+	switch nulls {
+	case NullsFirst:
+		order += " NULLS FIRST"
+	case NullsLast:
+		order += " NULLS LAST"
+	}
+	return order
+}
+
+func (postgresDialect) ILike(column, placeholder string) string {
+	return fmt.Sprintf("%s ILIKE %s", column, placeholder)
+}
+
+func (postgresDialect) FullTextSearch(columns []string, vector, language, placeholder string) (string, string) {
+	if language == "" {
+		language = DefaultSearchLanguage
+	}
+	config := "'" + strings.ReplaceAll(language, "'", "''") + "'"
+	document := vector
+	if document == "" {
+		document = fmt.Sprintf("to_tsvector(%s, concat_ws(' ', %s))", config, strings.Join(columns, ", "))
+	}
+	query := fmt.Sprintf("plainto_tsquery(%s, %s)", config, placeholder)
+	return fmt.Sprintf("%s @@ %s", document, query), fmt.Sprintf("ts_rank(%s, %s)", document, query)
+}
+
+// JSONExtract 按数值提取时只转换 JSON 数字，其余类型的值为 NULL，避免转换失败导致整个查询报错
+func (postgresDialect) JSONExtract(column string, path []string, numeric bool) string {
+	jsonPath := strings.Join(path, ",")
+	expr := fmt.Sprintf("%s #>> '{%s}'", column, jsonPath)
+	if numeric {
+		return fmt.Sprintf("(CASE WHEN jsonb_typeof(%s::jsonb #> '{%s}') = 'number' THEN (%s)::numeric END)", column, jsonPath, expr)
+	}
+	return fmt.Sprintf("(%s)", expr)
+}
+
+func (postgresDialect) JSONContains(column, placeholder string) string {
+	return fmt.Sprintf("%s::jsonb @> %s::jsonb", column, placeholder)
+}
+
+// JSONMergePatch PostgreSQL 没有内置的合并函数，按 patch 的结构逐层生成表达式：
+// null 删除键，对象递归合并，其余值直接覆盖；非对象的原值按空对象处理
+func (d postgresDialect) JSONMergePatch(column string, patch map[string]any, startIndex int) (string, []any, error) {
+	return d.mergePatch(column+"::jsonb", patch, startIndex)
+}
+
+func (d postgresDialect) mergePatch(target string, patch map[string]any, startIndex int) (string, []any, error) {
+	var values []any
+	next := func(value any) string {
+		values = append(values, value)
+		return d.Placeholder(startIndex + len(values) - 1)
+	}
+
+	expr := fmt.Sprintf("(CASE WHEN jsonb_typeof(%s) = 'object' THEN %s ELSE '{}'::jsonb END)", target, target)
+	var pairs []string
+	for _, key := range sortedKeys(patch) {
+		switch v := patch[key].(type) {
+		case nil:
+			expr += fmt.Sprintf(" - %s::text", next(key))
+		case map[string]any:
+			keyPlaceholder := next(key)
+			child, childValues, err := d.mergePatch(fmt.Sprintf("(%s -> %s::text)", target, keyPlaceholder), v, startIndex+len(values))
+			if err != nil {
+				return "", nil, err
+			}
+			values = append(values, childValues...)
+			pairs = append(pairs, keyPlaceholder+"::text", child)
+		default:
+			data, err := json.Marshal(v)
+			if err != nil {
+				return "", nil, err
+			}
+			keyPlaceholder := next(key)
+			pairs = append(pairs, keyPlaceholder+"::text", next(string(data))+"::jsonb")
+		}
+	}
+	if len(pairs) > 0 {
+		expr = fmt.Sprintf("(%s || jsonb_build_object(%s))", expr, strings.Join(pairs, ", "))
+	}
+	return expr, values, nil
+}
+
+func (postgresDialect) ForeignKeysQuery() string {
+	return `SELECT tc.constraint_name AS constraint_name, kcu.column_name AS column_name,
+	ccu.table_name AS ref_table, ccu.column_name AS ref_column
+FROM information_schema.table_constraints tc
+JOIN information_schema.key_column_usage kcu
+	ON kcu.constraint_name = tc.constraint_name AND kcu.table_schema = tc.table_schema
+JOIN information_schema.constraint_column_usage ccu
+	ON ccu.constraint_name = tc.constraint_name AND ccu.table_schema = tc.table_schema
+WHERE tc.constraint_type = 'FOREIGN KEY' AND tc.table_schema = current_schema() AND tc.table_name = $1`
+}
+
+// CallRoutine PostgreSQL 通过 SELECT 调用函数，返回集合的函数按结果集展开
+func (d postgresDialect) CallRoutine(name string, args int, scalar bool) string {
+	if scalar {
+		return fmt.Sprintf("SELECT %s(%s) AS %s", name, routineArgs(d, args), d.Quote("result"))
+	}
+	return fmt.Sprintf("SELECT * FROM %s(%s)", name, routineArgs(d, args))
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string { return "mysql" }
+
+func (mysqlDialect) Placeholder(index int) string { return "?" }
+
+func (mysqlDialect) Quote(identifier string) string {
+	return "`" + strings.ReplaceAll(identifier, "`", "``") + "`"
+}
+
+func (mysqlDialect) SupportsReturning() bool { return false }
+
+// Upsert MySQL 按表上任意主键或唯一索引判断冲突，conflictColumns 仅用于无更新列时的占位赋值
+func (d mysqlDialect) Upsert(conflictColumns, updateColumns, extraSets []string) string {
+	if len(updateColumns) == 0 && len(extraSets) == 0 {
+		col := d.Quote(conflictColumns[0])
+		return fmt.Sprintf("ON DUPLICATE KEY UPDATE %s = %s", col, col)
+	}
+	sets := make([]string, len(updateColumns), len(updateColumns)+len(extraSets))
+	for i, col := range updateColumns {
+		sets[i] = fmt.Sprintf("%s = VALUES(%s)", d.Quote(col), d.Quote(col))
+	}
+	sets = append(sets, extraSets...)
+	return "ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", ")
+}
+
+func (mysqlDialect) DateTrunc(unit, column string) string {
+	switch unit {
+	case "hour":
+		return fmt.Sprintf("DATE_FORMAT(%s, '%%Y-%%m-%%d %%H:00:00')", column)
+	case "week":
+		return fmt.Sprintf("DATE_SUB(DATE(%s), INTERVAL WEEKDAY(%s) DAY)", column, column)
+	case "month":
+		return fmt.Sprintf("DATE_FORMAT(%s, '%%Y-%%m-01')", column)
+	default:
+		return fmt.Sprintf("DATE(%s)", column)
+	}
+}
+
+// OrderBy MySQL 不支持 NULLS FIRST/LAST，通过先按 IS NULL 排序模拟
+func (mysqlDialect) OrderBy(column string, desc bool, nulls string) string {
+	order := column
+	if desc {
+		order += " DESC"
+	}
+	switch nulls {
+	case NullsFirst:
+		return fmt.Sprintf("%s IS NULL DESC, %s", column, order)
+	case NullsLast:
+		return fmt.Sprintf("%s IS NULL, %s", column, order)
+	}
+	return order
+}
+
+func (mysqlDialect) ILike(column, placeholder string) string {
+	return fmt.Sprintf("LOWER(%s) LIKE LOWER(%s)", column, placeholder)
+}
+
+// FullTextSearch MySQL 要求 columns 上建有 FULLTEXT 索引，匹配条件与相关度使用同一个 MATCH 表达式
+func (mysqlDialect) FullTextSearch(columns []string, vector, language, placeholder string) (string, string) {
+	match := fmt.Sprintf("MATCH(%s) AGAINST(%s IN NATURAL LANGUAGE MODE)", strings.Join(columns, ", "), placeholder)
+	return match, match
+}
+
+func (mysqlDialect) JSONExtract(column string, path []string, numeric bool) string {
+	jsonPath := "$"
+	for _, key := range path {
+		jsonPath += `."` + key + `"`
+	}
+	if numeric {
+		extract := fmt.Sprintf("JSON_EXTRACT(%s, '%s')", column, jsonPath)
+		return fmt.Sprintf("(CASE WHEN JSON_TYPE(%s) IN ('INTEGER', 'UNSIGNED INTEGER', 'DOUBLE', 'DECIMAL') THEN CAST(%s AS DECIMAL(65,30)) END)", extract, extract)
+	}
+	return fmt.Sprintf("JSON_UNQUOTE(JSON_EXTRACT(%s, '%s'))", column, jsonPath)
+}
+
+func (mysqlDialect) JSONContains(column, placeholder string) string {
+	return fmt.Sprintf("JSON_CONTAINS(%s, %s)", column, placeholder)
+}
+
+// JSONMergePatch MySQL 内置的 JSON_MERGE_PATCH 即为 RFC 7396 语义
+func (d mysqlDialect) JSONMergePatch(column string, patch map[string]any, startIndex int) (string, []any, error) {
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return "", nil, err
+	}
+	return fmt.Sprintf("JSON_MERGE_PATCH(COALESCE(%s, JSON_OBJECT()), %s)", column, d.Placeholder(startIndex)), []any{string(data)}, nil
+}
+
+func (mysqlDialect) ForeignKeysQuery() string {
+	return `SELECT CONSTRAINT_NAME AS constraint_name, COLUMN_NAME AS column_name,
+	REFERENCED_TABLE_NAME AS ref_table, REFERENCED_COLUMN_NAME AS ref_column
+FROM information_schema.KEY_COLUMN_USAGE
+WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND REFERENCED_TABLE_NAME IS NOT NULL`
+}
+
+// CallRoutine MySQL 通过 SELECT 调用存储函数，通过 CALL 调用存储过程并返回其第一个结果集
+func (d mysqlDialect) CallRoutine(name string, args int, scalar bool) string {
+	if scalar {
+		return fmt.Sprintf("SELECT %s(%s) AS %s", name, routineArgs(d, args), d.Quote("result"))
+	}
+	return fmt.Sprintf("CALL %s(%s)", name, routineArgs(d, args))
+}
+
+// routineArgs 返回调用例程的参数占位符列表
+func routineArgs(d Dialect, args int) string {
+	placeholders := make([]string, args)
+	for i := range placeholders {
+		placeholders[i] = d.Placeholder(i + 1)
+	}
+	return strings.Join(placeholders, ", ")
+}
+
+var (
+	PostgresDialect Dialect = postgresDialect{}
+	MySQLDialect    Dialect = mysqlDialect{}
+)
+
+// DialectOf 根据驱动名称返回对应的方言
+func DialectOf(driver string) (Dialect, error) {
+	switch strings.ToLower(driver) {
+	case "postgres", "postgresql", "pgx":
+		return PostgresDialect, nil
+	case "mysql":
+		return MySQLDialect, nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s", driver)
+	}
+}
+
+// placeholders 生成从 start 开始的 n 个占位符
+func placeholders(d Dialect, start, n int) []string {
+	result := make([]string, n)
+	for i := 0; i < n; i++ {
+		result[i] = d.Placeholder(start + i)
+	}
+	return result
+}
+
+// queryRows 执行一条原始 SQL 并返回结果集
+func queryRows(chain *gom.Chain, query string, args ...any) ([]map[string]any, error) {
+	result := chain.Raw(query, args...).Exec()
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return result.Data, nil
+}
+
+// execAffected 执行一条原始 SQL 并返回受影响的行数
+func execAffected(chain *gom.Chain, query string, args ...any) (int64, error) {
+	result := chain.Raw(query, args...).Exec()
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected()
+}
+
+// buildInsert 构建 INSERT 语句，返回语句与参数
+func buildInsert(d Dialect, table string, data map[string]any) (string, []any) {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	columns := make([]string, len(keys))
+	values := make([]any, len(keys))
+	for i, k := range keys {
+		columns[i] = d.Quote(k)
+		values[i] = data[k]
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		d.Quote(table),
+		strings.Join(columns, ", "),
+		strings.Join(placeholders(d, 1, len(values)), ", "))
+	return query, values
+}
+
+// selectByKeys 按列值精确查询一行
+func selectByKeys(chain *gom.Chain, d Dialect, table string, keyColumns []string, keyValues []any) (map[string]any, error) {
+	conditions := make([]string, len(keyColumns))
+	for i, col := range keyColumns {
+		conditions[i] = fmt.Sprintf("%s = %s", d.Quote(col), d.Placeholder(i+1))
+	}
+
+	rows, err := queryRows(chain,
+		fmt.Sprintf("SELECT * FROM %s WHERE %s", d.Quote(table), strings.Join(conditions, " AND ")),
+		keyValues...)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	return rows[0], nil
+}
+
+// insertRow 插入一行并按方言取回插入后的完整数据
+// 支持 RETURNING 的数据库直接返回，否则按主键重新查询，未提供的自增主键取 LAST_INSERT_ID
+func insertRow(chain *gom.Chain, d Dialect, table string, primaryKeys []string, data map[string]any) (map[string]any, error) {
+	query, values := buildInsert(d, table, data)
+
+	if d.SupportsReturning() {
+		rows, err := queryRows(chain, query+" RETURNING *", values...)
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) == 0 {
+			return nil, nil
+		}
+		return rows[0], nil
+	}
+
+	result := chain.Raw(query, values...).Exec()
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if len(primaryKeys) == 0 {
+		return nil, nil
+	}
+
+	keyValues := make([]any, len(primaryKeys))
+	for i, pk := range primaryKeys {
+		pkVal, ok := data[pk]
+		if !ok || !isPrimaryKeyValid(pkVal) {
+			id, err := result.LastInsertId()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get last insert id: %w", err)
+			}
+			pkVal = id
+		}
+		keyValues[i] = pkVal
+	}
+
+	return selectByKeys(chain, d, table, primaryKeys, keyValues)
+}
+
+// upsertRow 插入一行，冲突时按 updateColumns 与 extraSets 更新已有记录，并返回最终的记录
+// data 中必须包含全部冲突列的值，用于在无法 RETURNING 时重新查询
+func upsertRow(chain *gom.Chain, d Dialect, table string, conflictColumns, updateColumns, extraSets []string, data map[string]any) (map[string]any, error) {
+	query, values := buildInsert(d, table, data)
+	query += " " + d.Upsert(conflictColumns, updateColumns, extraSets)
+
+	if d.SupportsReturning() && len(updateColumns)+len(extraSets) > 0 {
+		rows, err := queryRows(chain, query+" RETURNING *", values...)
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) > 0 {
+			return rows[0], nil
+		}
+	} else if _, err := execAffected(chain, query, values...); err != nil {
+		return nil, err
+	}
+
+	keyValues := make([]any, len(conflictColumns))
+	for i, col := range conflictColumns {
+		keyValues[i] = data[col]
+	}
+	return selectByKeys(chain, d, table, conflictColumns, keyValues)
+}
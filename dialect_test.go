@@ -0,0 +1,93 @@
+package crudo
+
+import (
+	"testing"
+
+	"github.com/kmlixh/gom/v4/define"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialectOf(t *testing.T) {
+	d, err := DialectOf("postgres")
+	assert.NoError(t, err)
+	assert.Equal(t, "$2", d.Placeholder(2))
+	assert.Equal(t, `"user"`, d.Quote("user"))
+	assert.True(t, d.SupportsReturning())
+
+	d, err = DialectOf("mysql")
+	assert.NoError(t, err)
+	assert.Equal(t, "?", d.Placeholder(2))
+	assert.Equal(t, "`user`", d.Quote("user"))
+	assert.False(t, d.SupportsReturning())
+
+	_, err = DialectOf("sqlite")
+	assert.Error(t, err)
+}
+
+func TestBuildConditionDialect(t *testing.T) {
+	param := ConditionParam{Key: "id", Op: define.OpIn, Values: []any{1, 2}}
+
+	cond, values := buildCondition(PostgresDialect, param, 3)
+	assert.Equal(t, `"id" IN ($3, $4)`, cond)
+	assert.Equal(t, []any{1, 2}, values)
+
+	cond, _ = buildCondition(MySQLDialect, param, 3)
+	assert.Equal(t, "`id` IN (?, ?)", cond)
+}
+
+func TestBuildConditionOperators(t *testing.T) {
+	cases := []struct {
+		param  ConditionParam
+		cond   string
+		values []any
+	}{
+		{ConditionParam{Key: "a", Op: define.OpNotIn, Values: 1}, `"a" NOT IN ($1)`, []any{1}},
+		{ConditionParam{Key: "a", Op: define.OpIsNull}, `"a" IS NULL`, nil},
+		{ConditionParam{Key: "a", Op: define.OpNotBetween, Values: []any{1, 5}}, `"a" NOT BETWEEN $1 AND $2`, []any{1, 5}},
+		{ConditionParam{Key: "a", Op: define.OpNotLike, Values: "x%"}, `"a" NOT LIKE $1`, []any{"x%"}},
+		{ConditionParam{Key: "a", Op: OpILike, Values: "x%"}, `"a" ILIKE $1`, []any{"x%"}},
+		{ConditionParam{Key: "a", Op: OpStartsWith, Values: "50%_off!"}, `"a" ILIKE $1 ESCAPE '!'`, []any{"50!%!_off!!%"}},
+		{ConditionParam{Key: "a", Op: OpContains, Values: "b"}, `"a" ILIKE $1 ESCAPE '!'`, []any{"%b%"}},
+	}
+	for _, tc := range cases {
+		cond, values := buildCondition(PostgresDialect, tc.param, 1)
+		assert.Equal(t, tc.cond, cond)
+		assert.Equal(t, tc.values, values)
+	}
+
+	cond, values := buildCondition(MySQLDialect, ConditionParam{Key: "a", Op: OpEndsWith, Values: "b"}, 1)
+	assert.Equal(t, "LOWER(`a`) LIKE LOWER(?) ESCAPE '!'", cond)
+	assert.Equal(t, []any{"%b"}, values)
+
+	cond, _ = buildCondition(PostgresDialect, ConditionParam{Key: "a", Op: define.OpBetween, Values: 1}, 1)
+	assert.Empty(t, cond)
+}
+
+func TestFilterValue(t *testing.T) {
+	intColumn := define.ColumnInfo{Name: "n", DataType: "int64"}
+	v, err := filterValue(define.OpIsNull, "", intColumn)
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+	_, err = filterValue(OpContains, "1", intColumn)
+	assert.Error(t, err)
+	_, err = filterValue(define.OpBetween, "1,2,3", intColumn)
+	assert.Error(t, err)
+	v, err = filterValue(OpContains, "a,b", define.ColumnInfo{Name: "s", DataType: "string"})
+	assert.NoError(t, err)
+	assert.Equal(t, "a,b", v)
+}
+
+func TestDialectUpsert(t *testing.T) {
+	assert.Equal(t, `ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name"`,
+		PostgresDialect.Upsert([]string{"id"}, []string{"name"}, nil))
+	assert.Equal(t, `ON CONFLICT ("a", "b") DO NOTHING`,
+		PostgresDialect.Upsert([]string{"a", "b"}, nil, nil))
+	assert.Equal(t, "ON DUPLICATE KEY UPDATE `name` = VALUES(`name`)",
+		MySQLDialect.Upsert([]string{"id"}, []string{"name"}, nil))
+	assert.Equal(t, "ON DUPLICATE KEY UPDATE `id` = `id`",
+		MySQLDialect.Upsert([]string{"id"}, nil, nil))
+	assert.Equal(t, `ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name", "version" = "t"."version" + 1`,
+		PostgresDialect.Upsert([]string{"id"}, []string{"name"}, []string{`"version" = "t"."version" + 1`}))
+	assert.Equal(t, "ON DUPLICATE KEY UPDATE `version` = `t`.`version` + 1",
+		MySQLDialect.Upsert([]string{"id"}, nil, []string{"`version` = `t`.`version` + 1"}))
+}
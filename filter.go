@@ -0,0 +1,267 @@
+package crudo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/kmlixh/gom/v4/define"
+)
+
+const PathQuery = "query"
+
+// 布尔过滤条件的默认限制
+const (
+	DefaultMaxFilterDepth      = 5
+	DefaultMaxFilterConditions = 50
+)
+
+// FilterNode 请求中的布尔过滤条件，每个节点只能是 and、or、not 分组或单个条件中的一种
+// 示例：{"or": [{"field": "status", "value": "active"}, {"field": "owner", "op": "eq", "value": "me"}]}
+type FilterNode struct {
+	And   []*FilterNode `json:"and,omitempty"`
+	Or    []*FilterNode `json:"or,omitempty"`
+	Not   *FilterNode   `json:"not,omitempty"`
+	Field string        `json:"field,omitempty"`
+	Op    string        `json:"op,omitempty"` // 与查询参数相同的操作符名称，为空时为 eq
+	Value any           `json:"value,omitempty"`
+}
+
+// QueryRequest query 操作的请求体
+type QueryRequest struct {
+	Filter      *FilterNode `json:"filter"`
+	Page        int         `json:"page"`
+	PageSize    int         `json:"pageSize"`
+	Sort        string      `json:"sort"` // 与查询参数 sort 相同的紧凑语法
+	Fields      []string    `json:"fields"`
+	WithDeleted bool        `json:"withDeleted"`
+	Q           string      `json:"q"`    // 搜索关键字，与 list/page 的 q 参数相同
+	Rank        bool        `json:"rank"` // 为 true 时先按搜索相关度降序排序
+	Expand      []string    `json:"expand"`
+}
+
+// ConditionGroup 解析后的布尔条件树，Logic 为 and、or、not，叶子节点只有 Condition
+type ConditionGroup struct {
+	Logic     string           `json:"logic,omitempty"`
+	Groups    []ConditionGroup `json:"groups,omitempty"`
+	Condition *ConditionParam  `json:"condition,omitempty"`
+}
+
+// WithFilterLimits 设置 query 操作中过滤条件的最大嵌套深度与最大条件数
+func WithFilterLimits(maxDepth, maxConditions int) CrudOption {
+	return func(c *Crud) {
+		if maxDepth > 0 {
+			c.MaxFilterDepth = maxDepth
+		}
+		if maxConditions > 0 {
+			c.MaxFilterConditions = maxConditions
+		}
+	}
+}
+
+func (c *Crud) requestToQuery() ParseRequestFunc {
+	return func(ctx *fiber.Ctx) (any, error) {
+		var req QueryRequest
+		if err := ctx.BodyParser(&req); err != nil {
+			return nil, fmt.Errorf("invalid request body: %w", err)
+		}
+
+		params := QueryParams{
+			Table:       c.Table,
+			Page:        req.Page,
+			PageSize:    req.PageSize,
+			WithDeleted: req.WithDeleted,
+			Search:      req.Q,
+			SearchRank:  req.Rank,
+			Expand:      req.Expand,
+		}
+		var invalid []InvalidParam
+		if req.Sort != "" {
+			sorts, sortInvalid := parseSort(req.Sort, c.TransferMap)
+			params.Sort = sorts
+			invalid = append(invalid, sortInvalid...)
+		}
+		for _, field := range req.Fields {
+			params.Fields = append(params.Fields, c.dbFieldName(field))
+		}
+
+		if req.Filter != nil {
+			conditions := 0
+			group, filterInvalid := c.resolveFilter(req.Filter, "filter", 1, &conditions)
+			invalid = append(invalid, filterInvalid...)
+			params.Filter = &group
+		}
+
+		if len(invalid) > 0 {
+			return nil, &InvalidParamsError{Params: invalid}
+		}
+		return params, nil
+	}
+}
+
+// resolveFilter 校验过滤条件并映射字段名、转换参数值，path 为当前节点在请求体中的位置，用于错误提示
+func (c *Crud) resolveFilter(node *FilterNode, path string, depth int, conditions *int) (ConditionGroup, []InvalidParam) {
+	if node == nil {
+		return ConditionGroup{}, []InvalidParam{{Name: path, Reason: "filter node cannot be null"}}
+	}
+	kinds := 0
+	for _, set := range []bool{node.And != nil, node.Or != nil, node.Not != nil, node.Field != ""} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return ConditionGroup{}, []InvalidParam{{Name: path, Reason: "filter node must have exactly one of and, or, not, field"}}
+	}
+	// 深度只计算分组，单个条件不占用层级
+	if node.Field == "" && depth > c.MaxFilterDepth {
+		return ConditionGroup{}, []InvalidParam{{Name: path, Reason: fmt.Sprintf("filter nesting exceeds %d levels", c.MaxFilterDepth)}}
+	}
+
+	if node.Not != nil {
+		child, invalid := c.resolveFilter(node.Not, path+".not", depth+1, conditions)
+		return ConditionGroup{Logic: "not", Groups: []ConditionGroup{child}}, invalid
+	}
+	if node.Field == "" {
+		logic, children := "and", node.And
+		if node.Or != nil {
+			logic, children = "or", node.Or
+		}
+		if len(children) == 0 {
+			return ConditionGroup{}, []InvalidParam{{Name: path + "." + logic, Reason: "filter group cannot be empty"}}
+		}
+		group := ConditionGroup{Logic: logic}
+		var invalid []InvalidParam
+		for i, child := range children {
+			resolved, childInvalid := c.resolveFilter(child, fmt.Sprintf("%s.%s[%d]", path, logic, i), depth+1, conditions)
+			group.Groups = append(group.Groups, resolved)
+			invalid = append(invalid, childInvalid...)
+		}
+		return group, invalid
+	}
+
+	*conditions++
+	if *conditions > c.MaxFilterConditions {
+		return ConditionGroup{}, []InvalidParam{{Name: path, Reason: fmt.Sprintf("filter exceeds %d conditions", c.MaxFilterConditions)}}
+	}
+	condition, err := c.resolveFilterCondition(node)
+	if err != nil {
+		return ConditionGroup{}, []InvalidParam{{Name: path, Reason: err.Error()}}
+	}
+	return ConditionGroup{Condition: condition}, nil
+}
+
+// resolveFilterCondition 将单个条件转换为 ConditionParam，参数值按列类型转换
+func (c *Crud) resolveFilterCondition(node *FilterNode) (*ConditionParam, error) {
+	name, path := splitJSONField(node.Field)
+	field := c.dbFieldName(name)
+	column, ok := c.queryBuilder.columnCache[field]
+	if !ok || (path != nil && !isJSONColumn(column)) {
+		return nil, fmt.Errorf("unknown column %s", node.Field)
+	}
+	opName := node.Op
+	if opName == "" {
+		opName = "eq"
+	}
+	op, ok := filterOps[opName]
+	if !ok {
+		return nil, fmt.Errorf("unsupported operator %s", opName)
+	}
+
+	// JSON 列按路径提取后比较，或对整列做包含判断
+	if isJSONColumn(column) {
+		op, err := jsonFilterOp(op, path)
+		if err != nil {
+			return nil, err
+		}
+		value, err := jsonConditionValue(op, node.Value)
+		if err != nil {
+			return nil, err
+		}
+		return &ConditionParam{Key: field, Path: path, Op: op, Values: value}, nil
+	}
+
+	var value any
+	switch op {
+	case define.OpIsNull, define.OpIsNotNull:
+	case define.OpIn, define.OpNotIn, define.OpBetween, define.OpNotBetween:
+		items, ok := node.Value.([]any)
+		if !ok {
+			items = []any{node.Value}
+		}
+		if (op == define.OpBetween || op == define.OpNotBetween) && len(items) != 2 {
+			return nil, errors.New("between requires exactly two values")
+		}
+		values := make([]any, len(items))
+		for i, item := range items {
+			converted, err := coerceFilterValue(column, item)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = converted
+		}
+		value = values
+	case define.OpLike, define.OpNotLike, OpILike, OpStartsWith, OpEndsWith, OpContains:
+		text, ok := node.Value.(string)
+		if !ok || column.DataType != "string" {
+			return nil, errors.New("operator requires a string column and a string value")
+		}
+		value = text
+	default:
+		converted, err := coerceFilterValue(column, node.Value)
+		if err != nil {
+			return nil, err
+		}
+		value = converted
+	}
+	return &ConditionParam{Key: field, Op: op, Values: value}, nil
+}
+
+// coerceFilterValue 转换过滤条件中的单个值，null 只能通过 isNull/isNotNull 判断
+func coerceFilterValue(column define.ColumnInfo, value any) (any, error) {
+	if value == nil {
+		return nil, errors.New("value cannot be null, use isNull or isNotNull")
+	}
+	converted, err := coerceValue(column, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for %s: %w", column.DataType, err)
+	}
+	return converted, nil
+}
+
+// buildGroupCondition 将条件树转换为 SQL 条件，分组都会加括号
+func buildGroupCondition(d Dialect, group ConditionGroup, startIndex int) (string, []any, error) {
+	if group.Condition != nil {
+		condition, values := buildCondition(d, *group.Condition, startIndex)
+		if condition == "" {
+			return "", nil, fmt.Errorf("invalid request body: unsupported condition on %s", group.Condition.Key)
+		}
+		return condition, values, nil
+	}
+
+	var conditions []string
+	var values []any
+	for _, child := range group.Groups {
+		condition, childValues, err := buildGroupCondition(d, child, startIndex+len(values))
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, condition)
+		values = append(values, childValues...)
+	}
+	if len(conditions) == 0 {
+		return "", nil, errors.New("invalid request body: empty filter group")
+	}
+
+	switch group.Logic {
+	case "and":
+		return "(" + strings.Join(conditions, " AND ") + ")", values, nil
+	case "or":
+		return "(" + strings.Join(conditions, " OR ") + ")", values, nil
+	case "not":
+		return "NOT (" + conditions[0] + ")", values, nil
+	default:
+		return "", nil, fmt.Errorf("invalid request body: unsupported filter logic %s", group.Logic)
+	}
+}
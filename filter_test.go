@@ -0,0 +1,61 @@
+package crudo
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/kmlixh/gom/v4/define"
+	"github.com/stretchr/testify/assert"
+)
+
+func newFilterTestCrud() *Crud {
+	return &Crud{
+		Dialect:             PostgresDialect,
+		TransferMap:         map[string]string{"ownerId": "owner_id"},
+		MaxFilterDepth:      3,
+		MaxFilterConditions: 4,
+		queryBuilder: &QueryBuilder{columnCache: map[string]define.ColumnInfo{
+			"status":   {Name: "status", DataType: "string"},
+			"owner_id": {Name: "owner_id", DataType: "int64"},
+		}},
+	}
+}
+
+func TestResolveFilter(t *testing.T) {
+	c := newFilterTestCrud()
+	var node FilterNode
+	assert.NoError(t, json.Unmarshal([]byte(`{"or": [
+		{"field": "status", "value": "active"},
+		{"and": [{"field": "ownerId", "op": "in", "value": [1, "2"]}, {"not": {"field": "status", "op": "isNull"}}]}
+	]}`), &node))
+
+	conditions := 0
+	group, invalid := c.resolveFilter(&node, "filter", 1, &conditions)
+	assert.Empty(t, invalid)
+
+	where, values, err := buildGroupCondition(c.Dialect, group, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, `("status" = $1 OR ("owner_id" IN ($2, $3) AND NOT ("status" IS NULL)))`, where)
+	assert.Equal(t, []any{"active", int64(1), int64(2)}, values)
+}
+
+func TestResolveFilterInvalid(t *testing.T) {
+	c := newFilterTestCrud()
+	cases := map[string]string{
+		"unknown column": `{"field": "missing", "value": 1}`,
+		"bad value":      `{"field": "ownerId", "value": "x"}`,
+		"null value":     `{"field": "status", "value": null}`,
+		"two kinds":      `{"field": "status", "value": "a", "or": [{"field": "status", "value": "b"}]}`,
+		"empty group":    `{"and": []}`,
+		"too deep":       `{"not": {"not": {"not": {"not": {"field": "status", "value": "a"}}}}}`,
+		"too many": `{"or": [{"field": "status", "value": "a"}, {"field": "status", "value": "b"},
+			{"field": "status", "value": "c"}, {"field": "status", "value": "d"}, {"field": "status", "value": "e"}]}`,
+	}
+	for name, body := range cases {
+		var node FilterNode
+		assert.NoError(t, json.Unmarshal([]byte(body), &node), name)
+		conditions := 0
+		_, invalid := c.resolveFilter(&node, "filter", 1, &conditions)
+		assert.NotEmpty(t, invalid, name)
+	}
+}
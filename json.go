@@ -0,0 +1,249 @@
+package crudo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kmlixh/gom/v4/define"
+)
+
+// MIMEMergePatchJSON update 请求使用该 Content-Type 时，JSON 列中的对象按 RFC 7396 合并到已有文档
+const MIMEMergePatchJSON = "application/merge-patch+json"
+
+// JSON 路径中允许的键名，路径会直接拼接到 SQL 中，因此只允许字母、数字、下划线与连字符
+var jsonPathKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// jsonMergePatch 需要与列中已有文档合并的 JSON 对象
+type jsonMergePatch map[string]any
+
+// isJSONColumn 判断列是否为 json/jsonb 类型
+func isJSONColumn(column define.ColumnInfo) bool {
+	switch strings.ToLower(column.TypeName) {
+	case "json", "jsonb":
+		return true
+	default:
+		return false
+	}
+}
+
+// splitJSONField 拆分 attrs.color 形式的字段名，返回列名与 JSON 路径
+func splitJSONField(field string) (string, []string) {
+	column, path, ok := strings.Cut(field, ".")
+	if !ok {
+		return field, nil
+	}
+	return column, strings.Split(path, ".")
+}
+
+// checkJSONPath 校验 JSON 路径中的每个键名
+func checkJSONPath(path []string) error {
+	for _, key := range path {
+		if !jsonPathKeyPattern.MatchString(key) {
+			return fmt.Errorf("invalid json path key %q", key)
+		}
+	}
+	return nil
+}
+
+// jsonFilterOp 校验 JSON 列上的操作符：带路径时按提取出的值比较；不带路径时只支持 contains（转换为包含判断）与空值判断
+func jsonFilterOp(op define.OpType, path []string) (define.OpType, error) {
+	if len(path) > 0 {
+		if err := checkJSONPath(path); err != nil {
+			return op, err
+		}
+		return op, nil
+	}
+	switch op {
+	case OpContains:
+		return OpJSONContains, nil
+	case define.OpIsNull, define.OpIsNotNull:
+		return op, nil
+	default:
+		return op, errors.New("json column only supports contains, isNull and isNotNull without a path")
+	}
+}
+
+// jsonFilterValue 解析 JSON 列过滤参数的值
+// 带路径时比较提取出的文本，大小比较的值都是数字时改为按数值比较；包含判断的值必须是合法的 JSON
+func jsonFilterValue(op define.OpType, v string) (any, error) {
+	switch op {
+	case define.OpIsNull, define.OpIsNotNull:
+		return nil, nil
+	case OpJSONContains:
+		if !json.Valid([]byte(v)) {
+			return nil, errors.New("contains requires a json document")
+		}
+		return v, nil
+	case define.OpLike, define.OpNotLike, OpILike, OpStartsWith, OpEndsWith, OpContains:
+		return v, nil
+	}
+
+	return jsonPathValues(op, strings.Split(v, ","))
+}
+
+// jsonConditionValue 解析 query 请求体中 JSON 列条件的值，规则与 jsonFilterValue 相同
+func jsonConditionValue(op define.OpType, value any) (any, error) {
+	switch op {
+	case define.OpIsNull, define.OpIsNotNull:
+		return nil, nil
+	case OpJSONContains:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	}
+
+	items, isList := value.([]any)
+	if !isList {
+		items = []any{value}
+	}
+	texts := make([]string, len(items))
+	for i, item := range items {
+		switch v := item.(type) {
+		case string:
+			texts[i] = v
+		case float64:
+			texts[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			texts[i] = strconv.FormatBool(v)
+		default:
+			return nil, errors.New("value must be a string, number or bool")
+		}
+	}
+	return jsonPathValues(op, texts)
+}
+
+// jsonPathValues 转换 JSON 路径条件的值：大小比较的值都是数字时按数值比较，否则按文本比较
+func jsonPathValues(op define.OpType, items []string) (any, error) {
+	if (op == define.OpBetween || op == define.OpNotBetween) && len(items) != 2 {
+		return nil, errors.New("between requires exactly two values")
+	}
+	values := make([]any, len(items))
+	for i, item := range items {
+		values[i] = item
+	}
+	switch op {
+	case define.OpGt, define.OpGe, define.OpLt, define.OpLe, define.OpBetween, define.OpNotBetween:
+		numbers := make([]any, len(items))
+		for i, item := range items {
+			number, err := strconv.ParseFloat(item, 64)
+			if err != nil {
+				numbers = nil
+				break
+			}
+			numbers[i] = number
+		}
+		if numbers != nil {
+			values = numbers
+		}
+	}
+	if len(values) == 1 && op != define.OpIn && op != define.OpNotIn {
+		return values[0], nil
+	}
+	return values, nil
+}
+
+// isNumericCondition 判断条件值是否全部为数值，用于决定 JSON 路径按数值还是文本比较
+func isNumericCondition(value any) bool {
+	switch v := value.(type) {
+	case float64:
+		return true
+	case []any:
+		for _, item := range v {
+			if _, ok := item.(float64); !ok {
+				return false
+			}
+		}
+		return len(v) > 0
+	default:
+		return false
+	}
+}
+
+// encodeJSONValue 将写入 JSON 列的值编码为 JSON 文本，字符串视为已编码的文本直接写入
+func encodeJSONValue(column define.ColumnInfo, value any) (any, error) {
+	switch v := value.(type) {
+	case nil:
+		if !column.IsNullable {
+			return nil, errors.New("column is not nullable")
+		}
+		return nil, nil
+	case string, jsonMergePatch:
+		return v, nil
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	}
+}
+
+// encodeJSONValues 编码写入数据中全部 JSON 列的值
+func (c *Crud) encodeJSONValues(data map[string]any) error {
+	for field, value := range data {
+		column, ok := c.queryBuilder.columnCache[field]
+		if !ok || !isJSONColumn(column) {
+			continue
+		}
+		encoded, err := encodeJSONValue(column, value)
+		if err != nil {
+			return fmt.Errorf("invalid request body: field %s: %w", field, err)
+		}
+		data[field] = encoded
+	}
+	return nil
+}
+
+// markMergePatch 将 JSON 列中的对象标记为需要与已有文档合并
+func (c *Crud) markMergePatch(data map[string]any) {
+	for field, value := range data {
+		object, ok := value.(map[string]any)
+		if !ok {
+			continue
+		}
+		if column, ok := c.queryBuilder.columnCache[field]; ok && isJSONColumn(column) {
+			data[field] = jsonMergePatch(object)
+		}
+	}
+}
+
+// decodeJSONColumns 将查询结果中 JSON 列的文本或字节解码为对象，无法解码时保留原值
+func (c *Crud) decodeJSONColumns(rows ...map[string]any) {
+	for field, column := range c.queryBuilder.columnCache {
+		if !isJSONColumn(column) {
+			continue
+		}
+		for _, row := range rows {
+			var raw []byte
+			switch v := row[field].(type) {
+			case []byte:
+				raw = v
+			case string:
+				raw = []byte(v)
+			default:
+				continue
+			}
+			var decoded any
+			if err := json.Unmarshal(raw, &decoded); err == nil {
+				row[field] = decoded
+			}
+		}
+	}
+}
+
+// sortedKeys 返回按字母排序的键，保证生成的 SQL 稳定
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,86 @@
+package crudo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/kmlixh/gom/v4/define"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONPathFilters(t *testing.T) {
+	columns := map[string]define.ColumnInfo{
+		"attrs": {Name: "attrs", TypeName: "jsonb", DataType: "string"},
+		"name":  {Name: "name", DataType: "string"},
+	}
+
+	var parsed any
+	var parseErr error
+	app := fiber.New()
+	app.Get("/", func(ctx *fiber.Ctx) error {
+		parsed, parseErr = RequestToQueryParamsTransferStrict("t", nil, columns)(ctx)
+		return nil
+	})
+
+	_, err := app.Test(httptest.NewRequest(http.MethodGet, `/?attrs.color_eq=red&attrs.size[gte]=10&attrs_contains={"tags":["a"]}`, nil))
+	assert.NoError(t, err)
+	assert.NoError(t, parseErr)
+
+	where := make(map[string]string)
+	for _, param := range parsed.(QueryParams).ConditionParams {
+		cond, _ := buildCondition(PostgresDialect, param, 1)
+		where[cond] = ""
+	}
+	assert.Contains(t, where, `("attrs" #>> '{color}') = $1`)
+	assert.Contains(t, where, `(CASE WHEN jsonb_typeof("attrs"::jsonb #> '{size}') = 'number' THEN ("attrs" #>> '{size}')::numeric END) >= $1`)
+	assert.Contains(t, where, `"attrs"::jsonb @> $1::jsonb`)
+
+	for _, query := range []string{"/?name.first_eq=a", "/?attrs.bad%27key_eq=a", "/?attrs_gt=1", `/?attrs_contains={bad`} {
+		_, err = app.Test(httptest.NewRequest(http.MethodGet, query, nil))
+		assert.NoError(t, err)
+		assert.Error(t, parseErr, query)
+	}
+}
+
+func TestMySQLJSONExtract(t *testing.T) {
+	assert.Equal(t, "JSON_UNQUOTE(JSON_EXTRACT(`attrs`, '$.\"a\".\"b\"'))", MySQLDialect.JSONExtract("`attrs`", []string{"a", "b"}, false))
+	assert.Equal(t, "(CASE WHEN JSON_TYPE(JSON_EXTRACT(`attrs`, '$.\"a\"')) IN ('INTEGER', 'UNSIGNED INTEGER', 'DOUBLE', 'DECIMAL') THEN CAST(JSON_EXTRACT(`attrs`, '$.\"a\"') AS DECIMAL(65,30)) END)", MySQLDialect.JSONExtract("`attrs`", []string{"a"}, true))
+}
+
+func TestJSONMergePatchClause(t *testing.T) {
+	data := map[string]any{
+		"attrs": jsonMergePatch{"color": "red", "old": nil, "size": map[string]any{"w": 2}},
+		"name":  "a",
+	}
+
+	sets, values, err := buildSetClause(PostgresDialect, data, 1)
+	assert.NoError(t, err)
+	base := `(CASE WHEN jsonb_typeof("attrs"::jsonb) = 'object' THEN "attrs"::jsonb ELSE '{}'::jsonb END)`
+	size := `("attrs"::jsonb -> $4::text)`
+	child := `((CASE WHEN jsonb_typeof(` + size + `) = 'object' THEN ` + size + ` ELSE '{}'::jsonb END) || jsonb_build_object($5::text, $6::jsonb))`
+	assert.Equal(t, `"attrs" = (`+base+` - $3::text || jsonb_build_object($1::text, $2::jsonb, $4::text, `+child+`)), "name" = $7`, sets)
+	assert.Equal(t, []any{"color", `"red"`, "old", "size", "w", "2", "a"}, values)
+
+	sets, values, err = buildSetClause(MySQLDialect, map[string]any{"attrs": jsonMergePatch{"old": nil}}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "`attrs` = JSON_MERGE_PATCH(COALESCE(`attrs`, JSON_OBJECT()), ?)", sets)
+	assert.Equal(t, []any{`{"old":null}`}, values)
+}
+
+func TestJSONColumnsEncodeDecode(t *testing.T) {
+	c := &Crud{queryBuilder: &QueryBuilder{columnCache: map[string]define.ColumnInfo{
+		"attrs": {Name: "attrs", TypeName: "json"},
+		"name":  {Name: "name", DataType: "string"},
+	}}}
+
+	data := map[string]any{"attrs": map[string]any{"a": 1.0}, "name": "x"}
+	assert.NoError(t, c.encodeJSONValues(data))
+	assert.Equal(t, `{"a":1}`, data["attrs"])
+
+	row := map[string]any{"attrs": []byte(`{"a":1}`), "name": `{"b":2}`}
+	c.decodeJSONColumns(row)
+	assert.Equal(t, map[string]any{"a": 1.0}, row["attrs"])
+	assert.Equal(t, `{"b":2}`, row["name"])
+}
@@ -0,0 +1,313 @@
+package crudo
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/kmlixh/gom/v4"
+	"github.com/kmlixh/gom/v4/define"
+)
+
+// 参数类型与 TransferType 使用的数据类型的对应关系
+var paramDataTypes = map[string]string{
+	"":       "string",
+	"string": "string",
+	"int":    "int64",
+	"float":  "float64",
+	"bool":   "bool",
+	"time":   "time.Time",
+}
+
+// NamedQuery 以 GET 接口暴露的自定义 SQL 查询
+// SQL 模板中的 :name 绑定为同名参数的占位符，声明的过滤列与分页作用于模板查询的结果
+type NamedQuery struct {
+	Name    string
+	Prefix  string
+	Db      *gom.DB
+	Dialect Dialect
+	Paging  bool
+	OrderBy string
+
+	sql     string                       // 参数替换为占位符后的 SQL
+	binds   []string                     // 每个占位符对应的参数名
+	params  map[string]QueryParamConfig  // 声明的参数
+	columns map[string]define.ColumnInfo // 参数与过滤列的类型，用于解析查询参数
+	filters map[string]bool              // 允许过滤的结果列
+}
+
+// NewNamedQuery 解析 SQL 模板并校验参数声明，模板中的每个参数都必须声明
+func NewNamedQuery(conf QueryConfig, db *gom.DB, dialect Dialect) (*NamedQuery, error) {
+	if conf.PathPrefix == "" || conf.SQL == "" {
+		return nil, fmt.Errorf("query %s requires a path prefix and sql", conf.Name)
+	}
+	q := &NamedQuery{
+		Name:    conf.Name,
+		Prefix:  conf.PathPrefix,
+		Db:      db,
+		Dialect: dialect,
+		Paging:  conf.Paging,
+		OrderBy: conf.OrderBy,
+		params:  make(map[string]QueryParamConfig, len(conf.Params)),
+		columns: make(map[string]define.ColumnInfo, len(conf.Params)+len(conf.Filters)),
+		filters: make(map[string]bool, len(conf.Filters)),
+	}
+
+	for _, param := range conf.Params {
+		column, err := paramColumn(param)
+		if err != nil {
+			return nil, fmt.Errorf("query %s: %w", conf.Name, err)
+		}
+		if _, exists := q.columns[param.Name]; exists {
+			return nil, fmt.Errorf("query %s: duplicate parameter %s", conf.Name, param.Name)
+		}
+		if param.Default != "" {
+			if _, err := TransferType(column)(param.Default); err != nil {
+				return nil, fmt.Errorf("query %s: invalid default of parameter %s: %w", conf.Name, param.Name, err)
+			}
+		}
+		q.params[param.Name] = param
+		q.columns[param.Name] = column
+	}
+	for _, filter := range conf.Filters {
+		column, err := paramColumn(filter)
+		if err != nil {
+			return nil, fmt.Errorf("query %s: %w", conf.Name, err)
+		}
+		if _, exists := q.columns[filter.Name]; exists {
+			return nil, fmt.Errorf("query %s: filter %s conflicts with a parameter", conf.Name, filter.Name)
+		}
+		q.columns[filter.Name] = column
+		q.filters[filter.Name] = true
+	}
+
+	q.sql, q.binds = compileNamedSQL(conf.SQL, dialect)
+	for _, name := range q.binds {
+		if _, ok := q.params[name]; !ok {
+			return nil, fmt.Errorf("query %s: parameter %s is not declared", conf.Name, name)
+		}
+	}
+	return q, nil
+}
+
+// paramColumn 将参数声明转换为解析查询参数使用的列信息
+func paramColumn(param QueryParamConfig) (define.ColumnInfo, error) {
+	if param.Name == "" {
+		return define.ColumnInfo{}, errors.New("parameter requires a name")
+	}
+	dataType, ok := paramDataTypes[param.Type]
+	if !ok {
+		return define.ColumnInfo{}, fmt.Errorf("unsupported type of parameter %s: %s", param.Name, param.Type)
+	}
+	return define.ColumnInfo{Name: param.Name, DataType: dataType, IsNullable: !param.Required}, nil
+}
+
+// compileNamedSQL 将 SQL 模板中的 :name 替换为方言的占位符，返回替换后的 SQL 与每个占位符对应的参数名
+// 字符串常量中的冒号与 PostgreSQL 的 :: 类型转换保持不变
+func compileNamedSQL(sql string, d Dialect) (string, []string) {
+	var b strings.Builder
+	var binds []string
+	inString := false
+	for i := 0; i < len(sql); i++ {
+		ch := sql[i]
+		switch {
+		case ch == '\'':
+			inString = !inString
+		case inString || ch != ':':
+		case i+1 < len(sql) && sql[i+1] == ':':
+			b.WriteString("::")
+			i++
+			continue
+		case i+1 < len(sql) && isNameStart(sql[i+1]):
+			j := i + 1
+			for j < len(sql) && (isNameStart(sql[j]) || (sql[j] >= '0' && sql[j] <= '9')) {
+				j++
+			}
+			binds = append(binds, sql[i+1:j])
+			b.WriteString(d.Placeholder(len(binds)))
+			i = j - 1
+			continue
+		}
+		b.WriteByte(ch)
+	}
+	return b.String(), binds
+}
+
+func isNameStart(ch byte) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+// Handle 处理 list 与 page 请求
+func (q *NamedQuery) Handle(ctx *fiber.Ctx, operation string) error {
+	if operation != PathList && (operation != PathPage || !q.Paging) {
+		return ctx.Status(http.StatusNotFound).JSON(fiber.Map{"error": "operation not configured"})
+	}
+	if ctx.Method() != http.MethodGet {
+		return ctx.Status(http.StatusMethodNotAllowed).JSON(fiber.Map{"error": "method not allowed"})
+	}
+
+	input, err := requestToQueryParams(q.Name, nil, q.columns, true)(ctx)
+	if err != nil {
+		return RenderErrs(ctx, err)
+	}
+	raw := make(map[string]string, len(q.params))
+	for name := range q.params {
+		if ctx.Request().URI().QueryArgs().Has(name) {
+			raw[name] = ctx.Query(name)
+		}
+	}
+
+	var result any
+	if operation == PathPage {
+		result, err = q.page(input.(QueryParams), raw)
+	} else {
+		result, err = q.list(input.(QueryParams), raw)
+	}
+	if err != nil {
+		return RenderErrs(ctx, err)
+	}
+	return RenderOk(ctx, result)
+}
+
+// bind 按占位符顺序返回参数值，缺少的参数使用默认值，没有默认值时绑定 NULL；同时返回结果列上的过滤条件
+// 参数值取自原始查询参数，不按逗号拆分
+func (q *NamedQuery) bind(params QueryParams, raw map[string]string) ([]any, []ConditionParam, error) {
+	var filters []ConditionParam
+	var invalid []InvalidParam
+	for _, cond := range params.ConditionParams {
+		if q.filters[cond.Key] {
+			filters = append(filters, cond)
+		} else if cond.Op != define.OpEq {
+			invalid = append(invalid, InvalidParam{Name: cond.Key, Reason: "parameter only supports equality"})
+		}
+	}
+
+	names := make([]string, 0, len(q.params))
+	for name := range q.params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	provided := make(map[string]any, len(names))
+	for _, name := range names {
+		param := q.params[name]
+		v, ok := raw[name]
+		if !ok {
+			v = param.Default
+		}
+		if v == "" {
+			if param.Required {
+				invalid = append(invalid, InvalidParam{Name: name, Reason: "required"})
+			}
+			continue
+		}
+		value, err := TransferType(q.columns[name])(v)
+		if err != nil {
+			invalid = append(invalid, InvalidParam{Name: name, Reason: err.Error()})
+			continue
+		}
+		provided[name] = value
+	}
+	if len(invalid) > 0 {
+		return nil, nil, &InvalidParamsError{Params: invalid}
+	}
+
+	values := make([]any, len(q.binds))
+	for i, name := range q.binds {
+		values[i] = provided[name]
+	}
+	return values, filters, nil
+}
+
+// from 返回查询的 FROM 子句与条件，存在过滤条件或分页时将模板包装为子查询
+func (q *NamedQuery) from(params QueryParams, raw map[string]string, wrap bool) (string, []any, error) {
+	values, filters, err := q.bind(params, raw)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(filters) == 0 && !wrap {
+		return q.sql, values, nil
+	}
+
+	query := fmt.Sprintf("SELECT * FROM (%s) AS %s", q.sql, q.Dialect.Quote("q"))
+	conditions := make([]string, 0, len(filters))
+	for _, filter := range filters {
+		condition, args := buildCondition(q.Dialect, filter, len(values)+1)
+		if condition == "" {
+			return "", nil, fmt.Errorf("invalid request body: unsupported condition on %s", filter.Key)
+		}
+		conditions = append(conditions, condition)
+		values = append(values, args...)
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	return query, values, nil
+}
+
+// list 返回查询的全部结果
+func (q *NamedQuery) list(params QueryParams, raw map[string]string) ([]map[string]any, error) {
+	query, values, err := q.from(params, raw, q.OrderBy != "")
+	if err != nil {
+		return nil, err
+	}
+	if q.OrderBy != "" {
+		query += " ORDER BY " + q.OrderBy
+	}
+	rows, err := queryRows(q.Db.Chain(), query, values...)
+	if err != nil {
+		return nil, fmt.Errorf("query %s failed: %w", q.Name, err)
+	}
+	return rows, nil
+}
+
+// page 分页返回查询结果
+func (q *NamedQuery) page(params QueryParams, raw map[string]string) (*PageInfo, error) {
+	page, pageSize := params.Page, params.PageSize
+	if pageSize == 0 {
+		pageSize = 10
+	}
+	if page == 0 {
+		page = 1
+	}
+
+	query, values, err := q.from(params, raw, true)
+	if err != nil {
+		return nil, err
+	}
+	countQuery := fmt.Sprintf("SELECT COUNT(*) AS %s FROM (%s) AS %s", q.Dialect.Quote("total"), query, q.Dialect.Quote("t"))
+	rows, err := queryRows(q.Db.Chain(), countQuery, values...)
+	if err != nil {
+		return nil, fmt.Errorf("query %s failed: %w", q.Name, err)
+	}
+	var total int64
+	if len(rows) > 0 {
+		if total, err = toInt64(rows[0]["total"]); err != nil {
+			return nil, err
+		}
+	}
+
+	if q.OrderBy != "" {
+		query += " ORDER BY " + q.OrderBy
+	}
+	query += fmt.Sprintf(" LIMIT %d OFFSET %d", pageSize, (page-1)*pageSize)
+	rows, err = queryRows(q.Db.Chain(), query, values...)
+	if err != nil {
+		return nil, fmt.Errorf("query %s failed: %w", q.Name, err)
+	}
+
+	pages := int((total + int64(pageSize) - 1) / int64(pageSize))
+	return &PageInfo{
+		PageNum:     page,
+		PageSize:    pageSize,
+		Total:       total,
+		Pages:       pages,
+		HasPrev:     page > 1,
+		HasNext:     page < pages,
+		List:        rows,
+		IsFirstPage: page == 1,
+		IsLastPage:  page >= pages,
+	}, nil
+}
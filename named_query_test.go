@@ -0,0 +1,91 @@
+package crudo
+
+import (
+	"testing"
+
+	"github.com/kmlixh/gom/v4/define"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompileNamedSQL(t *testing.T) {
+	sql, binds := compileNamedSQL("SELECT o.id, o.created_at::date FROM orders o WHERE o.status = :status AND o.note <> ':skip' AND o.total > :min_total OR o.status = :status", PostgresDialect)
+	assert.Equal(t, "SELECT o.id, o.created_at::date FROM orders o WHERE o.status = $1 AND o.note <> ':skip' AND o.total > $2 OR o.status = $3", sql)
+	assert.Equal(t, []string{"status", "min_total", "status"}, binds)
+
+	sql, _ = compileNamedSQL("SELECT * FROM orders WHERE status = :status", MySQLDialect)
+	assert.Equal(t, "SELECT * FROM orders WHERE status = ?", sql)
+}
+
+func TestNewNamedQuery(t *testing.T) {
+	conf := QueryConfig{
+		Name:       "order_report",
+		PathPrefix: "/reports/orders",
+		SQL:        "SELECT c.name, SUM(o.total) AS total FROM orders o JOIN customers c ON c.id = o.customer_id WHERE o.created_at >= :since AND c.country = :country GROUP BY c.name",
+		Params: []QueryParamConfig{
+			{Name: "since", Type: "time", Required: true},
+			{Name: "country", Default: "CN"},
+		},
+		Filters: []QueryParamConfig{{Name: "total", Type: "float"}, {Name: "name"}},
+		Paging:  true,
+	}
+	q, err := NewNamedQuery(conf, nil, PostgresDialect)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"since", "country"}, q.binds)
+
+	bad := conf
+	bad.Params = conf.Params[:1]
+	_, err = NewNamedQuery(bad, nil, PostgresDialect)
+	assert.ErrorContains(t, err, "parameter country is not declared")
+
+	bad = conf
+	bad.Params = []QueryParamConfig{{Name: "since", Type: "date"}, {Name: "country"}}
+	_, err = NewNamedQuery(bad, nil, PostgresDialect)
+	assert.Error(t, err)
+
+	bad = conf
+	bad.Params = []QueryParamConfig{{Name: "since", Type: "int", Default: "x"}, {Name: "country"}}
+	_, err = NewNamedQuery(bad, nil, PostgresDialect)
+	assert.Error(t, err)
+
+	bad = conf
+	bad.Filters = []QueryParamConfig{{Name: "country"}}
+	_, err = NewNamedQuery(bad, nil, PostgresDialect)
+	assert.Error(t, err)
+}
+
+func TestNamedQueryBind(t *testing.T) {
+	q, err := NewNamedQuery(QueryConfig{
+		Name:       "order_report",
+		PathPrefix: "/reports/orders",
+		SQL:        "SELECT name, total FROM order_totals WHERE country = :country AND year = :year",
+		Params: []QueryParamConfig{
+			{Name: "country", Default: "CN"},
+			{Name: "year", Type: "int", Required: true},
+		},
+		Filters: []QueryParamConfig{{Name: "total", Type: "float"}},
+	}, nil, PostgresDialect)
+	assert.NoError(t, err)
+
+	params := QueryParams{ConditionParams: []ConditionParam{
+		{Key: "year", Op: define.OpEq, Values: int64(2024)},
+		{Key: "total", Op: define.OpGt, Values: float64(100)},
+	}}
+	query, values, err := q.from(params, map[string]string{"year": "2024"}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, `SELECT * FROM (SELECT name, total FROM order_totals WHERE country = $1 AND year = $2) AS "q" WHERE "total" > $3`, query)
+	assert.Equal(t, []any{"CN", int64(2024), float64(100)}, values)
+
+	// 参数值不按逗号拆分
+	_, values, err = q.from(QueryParams{}, map[string]string{"year": "2024", "country": "a,b"}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"a,b", int64(2024)}, values)
+
+	var paramsErr *InvalidParamsError
+	_, _, err = q.from(QueryParams{}, map[string]string{"year": "x"}, false)
+	assert.ErrorAs(t, err, &paramsErr)
+	_, _, err = q.from(QueryParams{}, nil, false)
+	assert.ErrorAs(t, err, &paramsErr)
+	assert.Equal(t, []InvalidParam{{Name: "year", Reason: "required"}}, paramsErr.Params)
+	_, _, err = q.from(QueryParams{ConditionParams: []ConditionParam{{Key: "year", Op: define.OpGt, Values: int64(1)}}}, map[string]string{"year": "1"}, false)
+	assert.ErrorAs(t, err, &paramsErr)
+}
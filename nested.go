@@ -0,0 +1,283 @@
+package crudo
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kmlixh/gom/v4"
+	"github.com/kmlixh/gom/v4/define"
+)
+
+// update 中未出现的已有子记录的处理策略
+const (
+	MissingChildrenKeep   = "keep"
+	MissingChildrenDelete = "delete"
+	MissingChildrenError  = "error"
+)
+
+// childWrite 请求中某个一对多关联的子记录
+type childWrite struct {
+	relation *Relation
+	records  []map[string]any
+}
+
+// takeChildren 从请求数据中取出一对多关联的子记录数组，子记录的字段按关联表的 TransferMap 映射
+func (c *Crud) takeChildren(data map[string]any) ([]childWrite, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := make([]string, 0, len(c.Relations))
+	for name, rel := range c.Relations {
+		if _, ok := data[name]; ok && rel.Many {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	children := make([]childWrite, 0, len(names))
+	for _, name := range names {
+		rel := c.Relations[name]
+		items, ok := data[name].([]any)
+		if !ok {
+			return nil, fmt.Errorf("invalid request body: %s must be an array", name)
+		}
+		delete(data, name)
+
+		records := make([]map[string]any, len(items))
+		for i, item := range items {
+			record, ok := item.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("invalid request body: %s[%d] must be an object", name, i)
+			}
+			mapped, err := rel.Target.transferData(record, false)
+			if err != nil {
+				return nil, err
+			}
+			records[i] = mapped
+		}
+		children = append(children, childWrite{relation: rel, records: records})
+	}
+	return children, nil
+}
+
+// writeChildren 在父记录所在的事务中写入子记录，并返回写入后父记录下的全部子记录
+// 子记录的关联列总是使用父记录的值；update 时带有已存在主键的子记录执行更新，其余新增，
+// 未出现的已有子记录按关联的 MissingChildren 策略保留、删除或报错
+func (c *Crud) writeChildren(chain *gom.Chain, parent map[string]any, children []childWrite, update bool) (map[string]any, error) {
+	result := make(map[string]any, len(children))
+	now := time.Now()
+	for _, child := range children {
+		rel, target := child.relation, child.relation.Target
+		if target.ReadOnly {
+			return nil, fmt.Errorf("relation %s: %w", rel.Name, target.readOnlyError())
+		}
+		parentKey := parent[rel.LocalField]
+		if parentKey == nil {
+			return nil, fmt.Errorf("relation %s: parent field %s is empty", rel.Name, rel.LocalField)
+		}
+		tableInfo, err := target.Db.GetTableInfo(target.Table)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get table info: %w", err)
+		}
+		if len(tableInfo.PrimaryKeys) == 0 {
+			return nil, fmt.Errorf("relation %s: table %s has no primary key", rel.Name, target.Table)
+		}
+		primaryKeys := tableInfo.PrimaryKeys
+
+		existing := make(map[string][]any)
+		if update {
+			rows, err := target.selectChildren(chain, rel, parentKey, primaryKeys)
+			if err != nil {
+				return nil, err
+			}
+			for _, row := range rows {
+				keyValues, err := target.childKeyValues(primaryKeys, row)
+				if err != nil {
+					return nil, fmt.Errorf("relation %s: %w", rel.Name, err)
+				}
+				existing[childKey(keyValues)] = keyValues
+			}
+		}
+
+		for _, record := range child.records {
+			record[rel.ForeignField] = parentKey
+
+			keyValues, err := target.childKeyValues(primaryKeys, record)
+			if err != nil {
+				return nil, fmt.Errorf("relation %s: %w", rel.Name, err)
+			}
+			key := childKey(keyValues)
+			if current, ok := existing[key]; ok {
+				delete(existing, key)
+				if err := target.updateChild(chain, primaryKeys, current, record, now); err != nil {
+					return nil, fmt.Errorf("relation %s: %w", rel.Name, err)
+				}
+				continue
+			}
+
+			// 新记录中为 null 的主键视为未提供，由 checkInsertKeys 判断是否允许自动生成
+			for _, pk := range primaryKeys {
+				if record[pk] == nil {
+					delete(record, pk)
+				}
+			}
+			if err := target.coerceValues(record); err != nil {
+				return nil, fmt.Errorf("relation %s: %w", rel.Name, err)
+			}
+			if err := checkInsertKeys(tableInfo, record); err != nil {
+				return nil, fmt.Errorf("relation %s: %w", rel.Name, err)
+			}
+			if err := target.encodeJSONValues(record); err != nil {
+				return nil, err
+			}
+			target.fillInsertTimeFields(record, now)
+			if _, err := insertRow(chain, target.Dialect, target.Table, primaryKeys, record); err != nil {
+				return nil, fmt.Errorf("relation %s: %w", rel.Name, err)
+			}
+		}
+
+		if len(existing) > 0 {
+			switch rel.MissingChildren {
+			case MissingChildrenError:
+				return nil, fmt.Errorf("invalid request body: %d existing %s records are missing from the update", len(existing), rel.Name)
+			case MissingChildrenDelete:
+				for _, keyValues := range existing {
+					if err := target.deleteChild(chain, primaryKeys, keyValues); err != nil {
+						return nil, fmt.Errorf("relation %s: %w", rel.Name, err)
+					}
+				}
+			}
+		}
+
+		fields := target.FieldOfDetail
+		rows, err := target.selectChildren(chain, rel, parentKey, fields)
+		if err != nil {
+			return nil, err
+		}
+		target.decodeJSONColumns(rows...)
+		transferred := make([]map[string]any, len(rows))
+		for i, row := range rows {
+			if transferred[i], err = target.transferData(row, true); err != nil {
+				return nil, err
+			}
+		}
+		result[rel.Name] = transferred
+	}
+	return result, nil
+}
+
+// selectChildren 在事务中查询父记录下未删除的子记录，fields 为空时查询全部列
+func (c *Crud) selectChildren(chain *gom.Chain, rel *Relation, parentKey any, fields []string) ([]map[string]any, error) {
+	where, values, err := c.whereClause(QueryParams{
+		ConditionParams: []ConditionParam{{Key: rel.ForeignField, Op: define.OpEq, Values: parentKey}},
+	}, 1)
+	if err != nil {
+		return nil, err
+	}
+	columns := "*"
+	if len(fields) > 0 {
+		columns = strings.Join(c.quoteFields(fields), ", ")
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s", columns, c.Dialect.Quote(c.Table), where)
+	return queryRows(chain, query, values...)
+}
+
+// updateChild 按主键更新一条子记录，版本列由服务端维护
+func (c *Crud) updateChild(chain *gom.Chain, primaryKeys []string, keyValues []any, record map[string]any, now time.Time) error {
+	for _, pk := range primaryKeys {
+		delete(record, pk)
+	}
+	if c.VersionField != "" {
+		delete(record, c.VersionField)
+	}
+	if err := c.coerceValues(record); err != nil {
+		return err
+	}
+	c.fillUpdateTimeFields(record, now)
+
+	increment := c.versionIncrement()
+	if c.VersionField != "" && increment == "" {
+		record[c.VersionField] = now
+	}
+	sets, values, err := buildSetClause(c.Dialect, record, 1)
+	if err != nil {
+		return err
+	}
+	if increment != "" {
+		if sets != "" {
+			sets += ", "
+		}
+		sets += increment
+	}
+	if sets == "" {
+		return nil
+	}
+
+	conditions := make([]string, len(primaryKeys))
+	for i, pk := range primaryKeys {
+		conditions[i] = fmt.Sprintf("%s = %s", c.Dialect.Quote(pk), c.Dialect.Placeholder(len(values)+1))
+		values = append(values, keyValues[i])
+	}
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s", c.Dialect.Quote(c.Table), sets, strings.Join(conditions, " AND "))
+	_, err = execAffected(chain, query, values...)
+	return err
+}
+
+// deleteChild 按主键删除一条子记录，配置了软删除时只设置删除标记
+func (c *Crud) deleteChild(chain *gom.Chain, primaryKeys []string, keyValues []any) error {
+	var query string
+	var values []any
+	if c.SoftDeleteField != "" {
+		deleted, _ := c.softDeleteValues()
+		query = fmt.Sprintf("UPDATE %s SET %s = %s", c.Dialect.Quote(c.Table), c.Dialect.Quote(c.SoftDeleteField), c.Dialect.Placeholder(1))
+		values = append(values, deleted)
+	} else {
+		query = "DELETE FROM " + c.Dialect.Quote(c.Table)
+	}
+
+	conditions := make([]string, len(primaryKeys))
+	for i, pk := range primaryKeys {
+		conditions[i] = fmt.Sprintf("%s = %s", c.Dialect.Quote(pk), c.Dialect.Placeholder(len(values)+1))
+		values = append(values, keyValues[i])
+	}
+	_, err := execAffected(chain, query+" WHERE "+strings.Join(conditions, " AND "), values...)
+	return err
+}
+
+// childKeyValues 取出记录的主键值并按列类型转换，使请求中的 JSON 数字与数据库返回的整数类型一致
+func (c *Crud) childKeyValues(primaryKeys []string, record map[string]any) ([]any, error) {
+	columnInfo, err := c.queryBuilder.CacheTableInfo()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get table info: %w", err)
+	}
+	keyValues := make([]any, len(primaryKeys))
+	for i, pk := range primaryKeys {
+		value := record[pk]
+		if column, ok := columnInfo[pk]; ok && value != nil {
+			if value, err = coerceValue(column, value); err != nil {
+				return nil, fmt.Errorf("invalid request body: field %s: %w", pk, err)
+			}
+		}
+		keyValues[i] = value
+	}
+	return keyValues, nil
+}
+
+// childKey 将 childKeyValues 转换后的主键值拼接为可比较的字符串，任一主键为 nil 时返回空串
+func childKey(keyValues []any) string {
+	parts := make([]string, len(keyValues))
+	for i, v := range keyValues {
+		switch v := v.(type) {
+		case nil:
+			return ""
+		case float64:
+			parts[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			parts[i] = fmt.Sprint(v)
+		}
+	}
+	return strings.Join(parts, "\x00")
+}
@@ -0,0 +1,74 @@
+package crudo
+
+import (
+	"testing"
+
+	"github.com/kmlixh/gom/v4/define"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTakeChildren(t *testing.T) {
+	orders := &Crud{Table: "orders", queryBuilder: &QueryBuilder{columnCache: map[string]define.ColumnInfo{
+		"id":          {Name: "id"},
+		"customer_id": {Name: "customer_id"},
+	}}}
+	items := &Crud{Table: "order_items", TransferMap: map[string]string{"productId": "product_id"}, queryBuilder: &QueryBuilder{columnCache: map[string]define.ColumnInfo{
+		"id":         {Name: "id"},
+		"order_id":   {Name: "order_id"},
+		"product_id": {Name: "product_id"},
+	}}}
+	customers := &Crud{Table: "customers", queryBuilder: &QueryBuilder{columnCache: map[string]define.ColumnInfo{
+		"id": {Name: "id"},
+	}}}
+
+	assert.Error(t, orders.AddRelation(Relation{Name: "items", Target: items, LocalField: "id", ForeignField: "order_id", Many: true, MissingChildren: "drop"}))
+	assert.NoError(t, orders.AddRelation(Relation{Name: "items", Target: items, LocalField: "id", ForeignField: "order_id", Many: true, MissingChildren: MissingChildrenDelete}))
+	assert.NoError(t, orders.AddRelation(Relation{Name: "customer", Target: customers, LocalField: "customer_id", ForeignField: "id"}))
+
+	// 一对多关联的数组被取出并按子表的 TransferMap 映射，一对一关联不作为嵌套写入
+	data := map[string]any{
+		"customer_id": 1,
+		"customer":    map[string]any{"id": 1},
+		"items":       []any{map[string]any{"productId": 7}},
+	}
+	children, err := orders.takeChildren(data)
+	assert.NoError(t, err)
+	assert.Len(t, children, 1)
+	assert.Equal(t, "items", children[0].relation.Name)
+	assert.Equal(t, []map[string]any{{"product_id": 7}}, children[0].records)
+	assert.NotContains(t, data, "items")
+	assert.Contains(t, data, "customer")
+
+	_, err = orders.takeChildren(map[string]any{"items": map[string]any{"productId": 7}})
+	assert.ErrorContains(t, err, "invalid request body")
+	_, err = orders.takeChildren(map[string]any{"items": []any{7}})
+	assert.ErrorContains(t, err, "invalid request body")
+
+	children, err = orders.takeChildren(map[string]any{"customer_id": 1})
+	assert.NoError(t, err)
+	assert.Empty(t, children)
+}
+
+func TestChildKey(t *testing.T) {
+	// 请求中的数字与数据库返回的整数视为同一主键
+	assert.Equal(t, childKey([]any{int64(3)}), childKey([]any{float64(3)}))
+	assert.Equal(t, childKey([]any{1, "a"}), childKey([]any{int64(1), "a"}))
+	assert.NotEqual(t, childKey([]any{1, "a"}), childKey([]any{1, "b"}))
+	assert.Equal(t, "", childKey([]any{1, nil}))
+	assert.Equal(t, "1000000", childKey([]any{float64(1e6)}))
+
+	// 请求中 >= 1e6 的主键按列类型转换后仍与数据库中的同一行匹配
+	items := &Crud{Table: "order_items", queryBuilder: &QueryBuilder{columnCache: map[string]define.ColumnInfo{
+		"id":       {Name: "id", DataType: "int64", IsPrimaryKey: true},
+		"order_id": {Name: "order_id", DataType: "int64"},
+	}}}
+	fromRequest, err := items.childKeyValues([]string{"id"}, map[string]any{"id": float64(1000000), "order_id": float64(7)})
+	assert.NoError(t, err)
+	fromRow, err := items.childKeyValues([]string{"id"}, map[string]any{"id": int64(1000000), "order_id": int64(7)})
+	assert.NoError(t, err)
+	assert.Equal(t, []any{int64(1000000)}, fromRequest)
+	assert.Equal(t, childKey(fromRow), childKey(fromRequest))
+
+	_, err = items.childKeyValues([]string{"id"}, map[string]any{"id": "abc"})
+	assert.Error(t, err)
+}
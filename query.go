@@ -0,0 +1,197 @@
+package crudo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 排序时空值的位置
+const (
+	NullsFirst = "first"
+	NullsLast  = "last"
+)
+
+// SortField 单个排序项
+type SortField struct {
+	Field string `json:"field"`
+	Desc  bool   `json:"desc"`
+	Nulls string `json:"nulls"` // first 或 last，为空时使用数据库默认行为
+}
+
+// PageInfo 分页查询结果
+type PageInfo struct {
+	PageNum     int   `json:"pageNum"`
+	PageSize    int   `json:"pageSize"`
+	Total       int64 `json:"total"`
+	Pages       int   `json:"pages"`
+	HasPrev     bool  `json:"hasPrev"`
+	HasNext     bool  `json:"hasNext"`
+	List        any   `json:"list"`
+	IsFirstPage bool  `json:"isFirstPage"`
+	IsLastPage  bool  `json:"isLastPage"`
+}
+
+// parseSort 解析紧凑排序语法，如 sort=-created_at:nullsLast,name
+// 前缀 - 表示降序，后缀 :nullsFirst / :nullsLast 指定空值位置
+func parseSort(v string, transferMap map[string]string) ([]SortField, []InvalidParam) {
+	var sorts []SortField
+	var invalid []InvalidParam
+	for _, item := range strings.Split(v, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		sort := SortField{}
+		if strings.HasPrefix(item, "-") {
+			sort.Desc = true
+			item = item[1:]
+		} else {
+			item = strings.TrimPrefix(item, "+")
+		}
+		if field, nulls, ok := strings.Cut(item, ":"); ok {
+			item = field
+			switch strings.ToLower(nulls) {
+			case "nullsfirst":
+				sort.Nulls = NullsFirst
+			case "nullslast":
+				sort.Nulls = NullsLast
+			default:
+				invalid = append(invalid, InvalidParam{Name: "sort", Reason: "unsupported nulls option " + nulls})
+				continue
+			}
+		}
+		if dbField, ok := transferMap[item]; ok {
+			item = dbField
+		}
+		sort.Field = item
+		sorts = append(sorts, sort)
+	}
+	return sorts, invalid
+}
+
+// sortFields 汇总 orderBy、orderByDesc 与 sort 参数中的排序项，并校验列是否存在且允许排序
+func (c *Crud) sortFields(params QueryParams) ([]SortField, error) {
+	sorts := make([]SortField, 0, len(params.OrderBy)+len(params.OrderByDesc)+len(params.Sort))
+	for _, field := range params.OrderBy {
+		sorts = append(sorts, SortField{Field: field})
+	}
+	for _, field := range params.OrderByDesc {
+		sorts = append(sorts, SortField{Field: field, Desc: true})
+	}
+	sorts = append(sorts, params.Sort...)
+
+	var invalid []InvalidParam
+	for _, sort := range sorts {
+		if _, ok := c.queryBuilder.columnCache[sort.Field]; !ok {
+			invalid = append(invalid, InvalidParam{Name: c.apiFieldName(sort.Field), Reason: "unknown sort column"})
+		} else if len(c.SortableFields) > 0 && !contains(c.SortableFields, sort.Field) {
+			invalid = append(invalid, InvalidParam{Name: c.apiFieldName(sort.Field), Reason: "column is not sortable"})
+		}
+	}
+	if len(invalid) > 0 {
+		return nil, &InvalidParamsError{Params: invalid}
+	}
+	return sorts, nil
+}
+
+// orderClause 构建 ORDER BY 子句（不含 ORDER BY 关键字）
+func (c *Crud) orderClause(sorts []SortField) string {
+	orders := make([]string, len(sorts))
+	for i, sort := range sorts {
+		orders[i] = c.Dialect.OrderBy(c.Dialect.Quote(sort.Field), sort.Desc, sort.Nulls)
+	}
+	return strings.Join(orders, ", ")
+}
+
+// selectRows 按查询参数查询记录，limit 为 0 时不限制行数，并嵌入 expand 参数指定的关联记录
+func (c *Crud) selectRows(params QueryParams, fields []string, limit, offset int) ([]map[string]any, error) {
+	sorts, err := c.sortFields(params)
+	if err != nil {
+		return nil, err
+	}
+	relations, err := c.expandRelations(params.Expand)
+	if err != nil {
+		return nil, err
+	}
+	fields, extra := expandFields(fields, relations)
+	where, values, err := c.whereClause(params, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := "*"
+	if len(fields) > 0 {
+		quoted := make([]string, len(fields))
+		for i, field := range fields {
+			quoted[i] = c.Dialect.Quote(field)
+		}
+		columns = strings.Join(quoted, ", ")
+	}
+
+	var orders []string
+	if params.Search != "" && params.SearchRank {
+		rank, rankValues, err := c.searchRank(params.Search, len(values)+1)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, rank)
+		values = append(values, rankValues...)
+	}
+	if len(sorts) > 0 {
+		orders = append(orders, c.orderClause(sorts))
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s", columns, c.Dialect.Quote(c.Table))
+	if where != "" {
+		query += " WHERE " + where
+	}
+	if len(orders) > 0 {
+		query += " ORDER BY " + strings.Join(orders, ", ")
+	}
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	if offset > 0 {
+		query += fmt.Sprintf(" OFFSET %d", offset)
+	}
+
+	rows, err := queryRows(c.Db.Chain(), query, values...)
+	if err != nil {
+		return nil, err
+	}
+	c.decodeJSONColumns(rows...)
+
+	if err := c.expandRows(rows, relations); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		for _, field := range extra {
+			delete(row, field)
+		}
+	}
+	return rows, nil
+}
+
+// countRows 统计满足查询条件的记录数，countExpr 为空时使用 COUNT(*)
+func (c *Crud) countRows(params QueryParams, countExpr string) (int64, error) {
+	if countExpr == "" {
+		countExpr = "COUNT(*)"
+	}
+	where, values, err := c.whereClause(params, 1)
+	if err != nil {
+		return 0, err
+	}
+	query := fmt.Sprintf("SELECT %s AS %s FROM %s", countExpr, c.Dialect.Quote("total"), c.Dialect.Quote(c.Table))
+	if where != "" {
+		query += " WHERE " + where
+	}
+
+	rows, err := queryRows(c.Db.Chain(), query, values...)
+	if err != nil {
+		return 0, err
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	return toInt64(rows[0]["total"])
+}
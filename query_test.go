@@ -0,0 +1,48 @@
+package crudo
+
+import (
+	"testing"
+
+	"github.com/kmlixh/gom/v4/define"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSort(t *testing.T) {
+	sorts, invalid := parseSort("-createdAt:nullsLast, name ,+id", map[string]string{"createdAt": "created_at"})
+	assert.Empty(t, invalid)
+	assert.Equal(t, []SortField{
+		{Field: "created_at", Desc: true, Nulls: NullsLast},
+		{Field: "name"},
+		{Field: "id"},
+	}, sorts)
+
+	_, invalid = parseSort("name:nullsMiddle", nil)
+	assert.Len(t, invalid, 1)
+}
+
+func TestSortFieldsWhitelist(t *testing.T) {
+	c := &Crud{
+		Dialect:        PostgresDialect,
+		SortableFields: []string{"name"},
+		queryBuilder: &QueryBuilder{columnCache: map[string]define.ColumnInfo{
+			"id":   {Name: "id"},
+			"name": {Name: "name"},
+		}},
+	}
+
+	sorts, err := c.sortFields(QueryParams{OrderByDesc: []string{"name"}, Sort: []SortField{{Field: "name", Nulls: NullsFirst}}})
+	assert.NoError(t, err)
+	assert.Equal(t, `"name" DESC, "name" NULLS FIRST`, c.orderClause(sorts))
+
+	_, err = c.sortFields(QueryParams{OrderBy: []string{"id", "name; DROP TABLE t"}})
+	var paramsErr *InvalidParamsError
+	assert.ErrorAs(t, err, &paramsErr)
+	assert.Len(t, paramsErr.Params, 2)
+}
+
+func TestDialectOrderBy(t *testing.T) {
+	assert.Equal(t, `"a" DESC NULLS LAST`, PostgresDialect.OrderBy(`"a"`, true, NullsLast))
+	assert.Equal(t, "`a` IS NULL, `a` DESC", MySQLDialect.OrderBy("`a`", true, NullsLast))
+	assert.Equal(t, "`a` IS NULL DESC, `a`", MySQLDialect.OrderBy("`a`", false, NullsFirst))
+	assert.Equal(t, "`a`", MySQLDialect.OrderBy("`a`", false, ""))
+}
@@ -0,0 +1,56 @@
+package crudo
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/kmlixh/gom/v4/define"
+)
+
+// ReadOnlyPaths 只读表或视图注册的操作
+var ReadOnlyPaths = []string{PathGet, PathList, PathPage, PathCount, PathAggregate, PathQuery, PathTable}
+
+// ErrReadOnly 表示对只读表或视图执行了写操作
+var ErrReadOnly = errors.New("table is read-only")
+
+// readOnlyError 返回带表名的只读错误
+func (c *Crud) readOnlyError() error {
+	return fmt.Errorf("%w: %s", ErrReadOnly, c.Table)
+}
+
+// readOnlyOperation 只读模式下替代写操作，总是返回只读错误
+func (c *Crud) readOnlyOperation() DataOperationFunc {
+	return func(input any) (any, error) {
+		return nil, c.readOnlyError()
+	}
+}
+
+// checkIdentityCondition 配置了标识列时，get 必须提供标识列的等值条件
+func (c *Crud) checkIdentityCondition(params QueryParams) error {
+	if c.IdentityField == "" {
+		return nil
+	}
+	for _, cond := range params.ConditionParams {
+		if cond.Key == c.IdentityField && cond.Op == define.OpEq && len(cond.Path) == 0 {
+			if _, isList := cond.Values.([]any); !isList {
+				return nil
+			}
+		}
+	}
+	return &InvalidParamsError{Params: []InvalidParam{{
+		Name:   c.apiFieldName(c.IdentityField),
+		Reason: "get requires a single value of the identity field",
+	}}}
+}
+
+// identityKeys 返回唯一标识记录的列：配置了标识列时使用标识列，否则使用主键
+func (c *Crud) identityKeys() ([]string, error) {
+	if c.IdentityField != "" {
+		return []string{c.IdentityField}, nil
+	}
+	tableInfo, err := c.Db.GetTableInfo(c.Table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get table info: %w", err)
+	}
+	return tableInfo.PrimaryKeys, nil
+}
@@ -0,0 +1,70 @@
+package crudo
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/kmlixh/gom/v4/define"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadOnlyHandlers(t *testing.T) {
+	columns := map[string]define.ColumnInfo{
+		"order_no": {Name: "order_no", DataType: "string"},
+		"total":    {Name: "total", DataType: "float64"},
+	}
+	view := &Crud{Table: "order_summary", ReadOnly: true, IdentityField: "order_no", queryBuilder: &QueryBuilder{columnCache: columns}}
+	assert.NoError(t, view.InitDefaultHandler())
+	paths := make([]string, 0, len(view.HandlerMap))
+	for path := range view.HandlerMap {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	expected := append([]string{}, ReadOnlyPaths...)
+	sort.Strings(expected)
+	assert.Equal(t, expected, paths)
+	assert.Contains(t, paths, PathAggregate)
+	assert.Contains(t, paths, PathQuery)
+
+	// 写操作出现在 handlerFilters 中时也只返回只读错误
+	view = &Crud{Table: "order_summary", ReadOnly: true, handlerFilters: []string{PathGet, PathAggregate, PathQuery, PathSave, PathDelete}, queryBuilder: &QueryBuilder{columnCache: columns}}
+	assert.NoError(t, view.InitDefaultHandler())
+	assert.Len(t, view.HandlerMap, 5)
+
+	// 读操作出现在 handlerFilters 中时保持原有实现，不会被替换为只读错误
+	_, err := view.HandlerMap[PathAggregate].DataOperationFunc(AggregateRequest{})
+	assert.EqualError(t, err, "invalid request body: metrics cannot be empty")
+	for _, path := range []string{PathSave, PathDelete} {
+		_, err = view.HandlerMap[path].DataOperationFunc(map[string]any{"order_no": "A1"})
+		assert.ErrorIs(t, err, ErrReadOnly)
+	}
+	_, err = view.save(nil, map[string]any{"order_no": "A1"})
+	assert.ErrorIs(t, err, ErrReadOnly)
+	_, err = view.update(nil, map[string]any{"order_no": "A1"})
+	assert.ErrorIs(t, err, ErrReadOnly)
+	_, err = view.deleteRecords(nil, DeleteRequest{IDs: []any{"A1"}})
+	assert.ErrorIs(t, err, ErrReadOnly)
+}
+
+func TestCheckIdentityCondition(t *testing.T) {
+	view := &Crud{Table: "order_summary", IdentityField: "order_no"}
+	assert.NoError(t, view.checkIdentityCondition(QueryParams{ConditionParams: []ConditionParam{
+		{Key: "order_no", Op: define.OpEq, Values: "A1"},
+	}}))
+
+	var paramsErr *InvalidParamsError
+	for _, params := range []QueryParams{
+		{},
+		{ConditionParams: []ConditionParam{{Key: "total", Op: define.OpEq, Values: 1.0}}},
+		{ConditionParams: []ConditionParam{{Key: "order_no", Op: define.OpIn, Values: []any{"A1", "A2"}}}},
+		{ConditionParams: []ConditionParam{{Key: "order_no", Op: define.OpEq, Values: []any{"A1", "A2"}}}},
+	} {
+		assert.ErrorAs(t, view.checkIdentityCondition(params), &paramsErr)
+	}
+
+	keys, err := view.identityKeys()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"order_no"}, keys)
+
+	assert.NoError(t, (&Crud{}).checkIdentityCondition(QueryParams{}))
+}
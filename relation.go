@@ -0,0 +1,213 @@
+package crudo
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kmlixh/gom/v4/define"
+)
+
+// Relation 表之间的关联，用于 expand 参数嵌入关联记录
+type Relation struct {
+	Name         string // expand 参数中使用的名称，也是嵌入结果中的键
+	Target       *Crud  // 关联表
+	LocalField   string // 本表中的关联列
+	ForeignField string // 关联表中的列，默认为关联表主键或标识列
+	Many         bool   // 为 true 时为一对多，嵌入列表；否则嵌入单个对象
+	// MissingChildren 一对多关联在 update 中未出现的已有子记录的处理策略：keep（默认）、delete 或 error
+	MissingChildren string
+}
+
+// AddRelation 添加关联，校验两端的列是否存在，关联名称不能与本表的列同名
+func (c *Crud) AddRelation(rel Relation) error {
+	if rel.Name == "" || rel.Target == nil {
+		return fmt.Errorf("relation on table %s requires a name and a target", c.Table)
+	}
+	if _, ok := c.queryBuilder.columnCache[rel.Name]; ok {
+		return fmt.Errorf("relation %s: name conflicts with a column of table %s", rel.Name, c.Table)
+	}
+	if _, ok := c.queryBuilder.columnCache[rel.LocalField]; !ok {
+		return fmt.Errorf("relation %s: local field not found in table %s: %s", rel.Name, c.Table, rel.LocalField)
+	}
+	switch rel.MissingChildren {
+	case "", MissingChildrenKeep, MissingChildrenDelete, MissingChildrenError:
+	default:
+		return fmt.Errorf("relation %s: unsupported missing children policy: %s", rel.Name, rel.MissingChildren)
+	}
+	if rel.ForeignField == "" && rel.Many {
+		return fmt.Errorf("relation %s: one-to-many relation requires a foreign field", rel.Name)
+	}
+	if rel.ForeignField == "" {
+		identity, err := rel.Target.identityKeys()
+		if err != nil {
+			return fmt.Errorf("relation %s: %w", rel.Name, err)
+		}
+		if len(identity) != 1 {
+			return fmt.Errorf("relation %s: target table %s must have a single primary key or an identity field", rel.Name, rel.Target.Table)
+		}
+		rel.ForeignField = identity[0]
+	}
+	if _, ok := rel.Target.queryBuilder.columnCache[rel.ForeignField]; !ok {
+		return fmt.Errorf("relation %s: foreign field not found in table %s: %s", rel.Name, rel.Target.Table, rel.ForeignField)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Relations == nil {
+		c.Relations = make(map[string]*Relation)
+	}
+	if _, exists := c.Relations[rel.Name]; exists {
+		return fmt.Errorf("relation %s already defined on table %s", rel.Name, c.Table)
+	}
+	c.Relations[rel.Name] = &rel
+	return nil
+}
+
+// DiscoverRelations 从数据库的外键定义中发现关联，targets 为同一数据库中按表名索引的 Crud
+// 关联名称为去掉 _id 后缀的列名，已经声明的同名关联、与本表列同名的名称以及指向未配置表的外键会被跳过，复合外键不支持
+func (c *Crud) DiscoverRelations(targets map[string]*Crud) error {
+	rows, err := queryRows(c.Db.Chain(), c.Dialect.ForeignKeysQuery(), c.Table)
+	if err != nil {
+		return fmt.Errorf("failed to query foreign keys of table %s: %w", c.Table, err)
+	}
+
+	columns := make(map[string]int)
+	for _, row := range rows {
+		columns[fmt.Sprint(row["constraint_name"])]++
+	}
+	for _, row := range rows {
+		if columns[fmt.Sprint(row["constraint_name"])] > 1 {
+			continue
+		}
+		column := fmt.Sprint(row["column_name"])
+		target, ok := targets[fmt.Sprint(row["ref_table"])]
+		if !ok {
+			continue
+		}
+		name := strings.TrimSuffix(column, "_id")
+		if _, isColumn := c.queryBuilder.columnCache[name]; isColumn {
+			continue
+		}
+		c.mu.RLock()
+		_, exists := c.Relations[name]
+		c.mu.RUnlock()
+		if exists {
+			continue
+		}
+		if err := c.AddRelation(Relation{
+			Name:         name,
+			Target:       target,
+			LocalField:   column,
+			ForeignField: fmt.Sprint(row["ref_column"]),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expandRelations 按名称查找 expand 参数中的关联，未知名称返回 InvalidParamsError
+func (c *Crud) expandRelations(names []string) ([]*Relation, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	relations := make([]*Relation, 0, len(names))
+	var invalid []InvalidParam
+	for _, name := range names {
+		rel, ok := c.Relations[name]
+		if !ok {
+			invalid = append(invalid, InvalidParam{Name: "expand", Reason: "unknown relation " + name})
+			continue
+		}
+		relations = append(relations, rel)
+	}
+	if len(invalid) > 0 {
+		return nil, &InvalidParamsError{Params: invalid}
+	}
+	return relations, nil
+}
+
+// expandFields 在指定了返回字段时补充关联需要的本表列，返回补充后的字段与需要在返回前移除的列
+func expandFields(fields []string, relations []*Relation) ([]string, []string) {
+	if len(fields) == 0 {
+		return fields, nil
+	}
+	var extra []string
+	for _, rel := range relations {
+		if !contains(fields, rel.LocalField) && !contains(extra, rel.LocalField) {
+			extra = append(extra, rel.LocalField)
+		}
+	}
+	if len(extra) == 0 {
+		return fields, nil
+	}
+	return append(append([]string{}, fields...), extra...), extra
+}
+
+// expandRows 为每个关联执行一次批量查询，将关联记录按关联名称嵌入 rows
+// 关联记录使用关联表的 FieldOfDetail 与 TransferMap，并排除关联表中已软删除的记录
+func (c *Crud) expandRows(rows []map[string]any, relations []*Relation) error {
+	for _, rel := range relations {
+		keys := make([]any, 0, len(rows))
+		seen := make(map[string]bool)
+		for _, row := range rows {
+			value := row[rel.LocalField]
+			if value == nil {
+				continue
+			}
+			if id := fmt.Sprint(value); !seen[id] {
+				seen[id] = true
+				keys = append(keys, value)
+			}
+		}
+
+		grouped := make(map[string][]map[string]any)
+		if len(keys) > 0 {
+			target := rel.Target
+			fields, extra := target.FieldOfDetail, ""
+			if len(fields) > 0 && !contains(fields, rel.ForeignField) {
+				fields = append(append([]string{}, fields...), rel.ForeignField)
+				extra = rel.ForeignField
+			}
+			related, err := target.selectRows(QueryParams{
+				Table:           target.Table,
+				ConditionParams: []ConditionParam{{Key: rel.ForeignField, Op: define.OpIn, Values: keys}},
+			}, fields, 0, 0)
+			if err != nil {
+				return fmt.Errorf("expand %s failed: %w", rel.Name, err)
+			}
+			for _, item := range related {
+				id := fmt.Sprint(item[rel.ForeignField])
+				if extra != "" {
+					delete(item, extra)
+				}
+				transferred, err := target.transferData(item, true)
+				if err != nil {
+					return err
+				}
+				grouped[id] = append(grouped[id], transferred)
+			}
+		}
+
+		for _, row := range rows {
+			var matched []map[string]any
+			if value := row[rel.LocalField]; value != nil {
+				matched = grouped[fmt.Sprint(value)]
+			}
+			if rel.Many {
+				if matched == nil {
+					matched = []map[string]any{}
+				}
+				row[rel.Name] = matched
+			} else if len(matched) > 0 {
+				row[rel.Name] = matched[0]
+			} else {
+				row[rel.Name] = nil
+			}
+		}
+	}
+	return nil
+}
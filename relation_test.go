@@ -0,0 +1,51 @@
+package crudo
+
+import (
+	"testing"
+
+	"github.com/kmlixh/gom/v4/define"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelations(t *testing.T) {
+	categories := &Crud{Table: "categories", queryBuilder: &QueryBuilder{columnCache: map[string]define.ColumnInfo{
+		"id":   {Name: "id"},
+		"name": {Name: "name"},
+	}}}
+	products := &Crud{Table: "products", queryBuilder: &QueryBuilder{columnCache: map[string]define.ColumnInfo{
+		"id":          {Name: "id"},
+		"category_id": {Name: "category_id"},
+	}}}
+
+	assert.NoError(t, products.AddRelation(Relation{Name: "category", Target: categories, LocalField: "category_id", ForeignField: "id"}))
+	assert.Error(t, products.AddRelation(Relation{Name: "category", Target: categories, LocalField: "category_id", ForeignField: "id"}))
+	assert.Error(t, products.AddRelation(Relation{Name: "owner", Target: categories, LocalField: "owner_id", ForeignField: "id"}))
+	// 关联名称与列同名时会覆盖查询结果中的列值
+	assert.ErrorContains(t, products.AddRelation(Relation{Name: "category_id", Target: categories, LocalField: "category_id", ForeignField: "id"}), "conflicts with a column")
+	assert.Error(t, categories.AddRelation(Relation{Name: "products", Target: products, LocalField: "id", Many: true}))
+	assert.NoError(t, categories.AddRelation(Relation{Name: "products", Target: products, LocalField: "id", ForeignField: "category_id", Many: true}))
+
+	_, err := products.expandRelations([]string{"category", "owner"})
+	var paramsErr *InvalidParamsError
+	assert.ErrorAs(t, err, &paramsErr)
+
+	relations, err := products.expandRelations([]string{"category"})
+	assert.NoError(t, err)
+	fields, extra := expandFields([]string{"id"}, relations)
+	assert.Equal(t, []string{"id", "category_id"}, fields)
+	assert.Equal(t, []string{"category_id"}, extra)
+	fields, extra = expandFields(nil, relations)
+	assert.Nil(t, fields)
+	assert.Nil(t, extra)
+
+	// 关联列全部为空时不查询关联表
+	rows := []map[string]any{{"id": 1, "category_id": nil}}
+	assert.NoError(t, products.expandRows(rows, relations))
+	assert.Contains(t, rows[0], "category")
+	assert.Nil(t, rows[0]["category"])
+
+	many, _ := categories.expandRelations([]string{"products"})
+	rows = []map[string]any{{"id": nil}}
+	assert.NoError(t, categories.expandRows(rows, many))
+	assert.Equal(t, []map[string]any{}, rows[0]["products"])
+}
@@ -0,0 +1,187 @@
+package crudo
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/kmlixh/gom/v4"
+	"github.com/kmlixh/gom/v4/define"
+)
+
+// PathCall 调用函数或存储过程的接口
+const PathCall = "call"
+
+// 例程的返回结果形式
+const (
+	RoutineResultScalar = "scalar" // 单个值
+	RoutineResultRow    = "row"    // 单行，没有结果时返回 null
+	RoutineResultRows   = "rows"   // 多行
+)
+
+// Routine 以 POST 接口暴露的数据库函数或存储过程，请求体中的参数按声明的顺序传入
+type Routine struct {
+	Name    string
+	Prefix  string
+	Db      *gom.DB
+	Dialect Dialect
+	Result  string
+
+	params  []QueryParamConfig
+	columns []define.ColumnInfo
+	call    string
+}
+
+// NewRoutine 校验例程配置并生成调用语句，name 中的 schema 与例程名分别加引号
+func NewRoutine(conf RoutineConfig, db *gom.DB, dialect Dialect) (*Routine, error) {
+	if conf.Name == "" || conf.PathPrefix == "" {
+		return nil, fmt.Errorf("routine %s requires a name and a path prefix", conf.Name)
+	}
+	result := conf.Result
+	switch result {
+	case "":
+		result = RoutineResultRows
+	case RoutineResultScalar, RoutineResultRow, RoutineResultRows:
+	default:
+		return nil, fmt.Errorf("routine %s: unsupported result: %s", conf.Name, conf.Result)
+	}
+
+	r := &Routine{
+		Name:    conf.Name,
+		Prefix:  conf.PathPrefix,
+		Db:      db,
+		Dialect: dialect,
+		Result:  result,
+		params:  conf.Params,
+		columns: make([]define.ColumnInfo, len(conf.Params)),
+	}
+	seen := make(map[string]bool, len(conf.Params))
+	for i, param := range conf.Params {
+		column, err := paramColumn(param)
+		if err != nil {
+			return nil, fmt.Errorf("routine %s: %w", conf.Name, err)
+		}
+		if seen[param.Name] {
+			return nil, fmt.Errorf("routine %s: duplicate parameter %s", conf.Name, param.Name)
+		}
+		seen[param.Name] = true
+		if param.Default != "" {
+			if _, err := TransferType(column)(param.Default); err != nil {
+				return nil, fmt.Errorf("routine %s: invalid default of parameter %s: %w", conf.Name, param.Name, err)
+			}
+		}
+		r.columns[i] = column
+	}
+
+	parts := strings.Split(conf.Name, ".")
+	for i, part := range parts {
+		parts[i] = dialect.Quote(part)
+	}
+	r.call = dialect.CallRoutine(strings.Join(parts, "."), len(conf.Params), result == RoutineResultScalar)
+	return r, nil
+}
+
+// Handle 处理调用请求
+func (r *Routine) Handle(ctx *fiber.Ctx, operation string) error {
+	if operation != PathCall {
+		return ctx.Status(http.StatusNotFound).JSON(fiber.Map{"error": "operation not configured"})
+	}
+	if ctx.Method() != http.MethodPost {
+		return ctx.Status(http.StatusMethodNotAllowed).JSON(fiber.Map{"error": "method not allowed"})
+	}
+
+	body := make(map[string]any)
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&body); err != nil {
+			return RenderErrs(ctx, fmt.Errorf("invalid request body: %w", err))
+		}
+	}
+	result, err := r.Call(body)
+	if err != nil {
+		return RenderErrs(ctx, err)
+	}
+	return RenderOk(ctx, result)
+}
+
+// Call 转换参数并调用例程，按 Result 返回单个值、单行或多行
+func (r *Routine) Call(body map[string]any) (any, error) {
+	args, err := r.args(body)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := queryRows(r.Db.Chain(), r.call, args...)
+	if err != nil {
+		return nil, fmt.Errorf("call %s failed: %w", r.Name, err)
+	}
+
+	switch r.Result {
+	case RoutineResultScalar:
+		if len(rows) == 0 {
+			return nil, nil
+		}
+		return rows[0]["result"], nil
+	case RoutineResultRow:
+		if len(rows) == 0 {
+			return nil, nil
+		}
+		return rows[0], nil
+	default:
+		if rows == nil {
+			rows = []map[string]any{}
+		}
+		return rows, nil
+	}
+}
+
+// args 按声明顺序转换请求体中的参数，未知参数与无法转换的值返回 InvalidParamsError
+func (r *Routine) args(body map[string]any) ([]any, error) {
+	var invalid []InvalidParam
+	declared := make(map[string]bool, len(r.params))
+	args := make([]any, len(r.params))
+	for i, param := range r.params {
+		declared[param.Name] = true
+		value, ok := body[param.Name]
+		if !ok || value == nil {
+			if param.Default != "" {
+				args[i], _ = TransferType(r.columns[i])(param.Default)
+			} else if param.Required {
+				invalid = append(invalid, InvalidParam{Name: param.Name, Reason: "required"})
+			}
+			continue
+		}
+		arg, err := routineArg(r.columns[i], value)
+		if err != nil {
+			invalid = append(invalid, InvalidParam{Name: param.Name, Reason: err.Error()})
+			continue
+		}
+		args[i] = arg
+	}
+	for _, name := range sortedKeys(body) {
+		if !declared[name] {
+			invalid = append(invalid, InvalidParam{Name: name, Reason: "unknown parameter"})
+		}
+	}
+	if len(invalid) > 0 {
+		return nil, &InvalidParamsError{Params: invalid}
+	}
+	return args, nil
+}
+
+// routineArg 将 JSON 值转换为文本后交给 TransferType 转换为参数类型
+func routineArg(column define.ColumnInfo, value any) (any, error) {
+	var text string
+	switch v := value.(type) {
+	case string:
+		text = v
+	case float64:
+		text = strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		text = strconv.FormatBool(v)
+	default:
+		return nil, errors.New("value must be a string, number or bool")
+	}
+	return TransferType(column)(text)
+}
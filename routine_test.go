@@ -0,0 +1,75 @@
+package crudo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRoutine(t *testing.T) {
+	conf := RoutineConfig{
+		Name:       "billing.close_month",
+		PathPrefix: "/billing/close-month",
+		Params: []QueryParamConfig{
+			{Name: "month", Type: "time", Required: true},
+			{Name: "dry_run", Type: "bool", Default: "false"},
+		},
+		Result: RoutineResultScalar,
+	}
+	r, err := NewRoutine(conf, nil, PostgresDialect)
+	assert.NoError(t, err)
+	assert.Equal(t, `SELECT "billing"."close_month"($1, $2) AS "result"`, r.call)
+
+	conf.Result = ""
+	r, err = NewRoutine(conf, nil, PostgresDialect)
+	assert.NoError(t, err)
+	assert.Equal(t, RoutineResultRows, r.Result)
+	assert.Equal(t, `SELECT * FROM "billing"."close_month"($1, $2)`, r.call)
+
+	r, err = NewRoutine(conf, nil, MySQLDialect)
+	assert.NoError(t, err)
+	assert.Equal(t, "CALL `billing`.`close_month`(?, ?)", r.call)
+
+	conf.Result = RoutineResultScalar
+	r, err = NewRoutine(conf, nil, MySQLDialect)
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT `billing`.`close_month`(?, ?) AS `result`", r.call)
+
+	conf.Result = "table"
+	_, err = NewRoutine(conf, nil, PostgresDialect)
+	assert.Error(t, err)
+
+	conf.Result = ""
+	conf.Params = []QueryParamConfig{{Name: "month"}, {Name: "month"}}
+	_, err = NewRoutine(conf, nil, PostgresDialect)
+	assert.Error(t, err)
+}
+
+func TestRoutineArgs(t *testing.T) {
+	r, err := NewRoutine(RoutineConfig{
+		Name:       "transfer_funds",
+		PathPrefix: "/accounts/transfer",
+		Params: []QueryParamConfig{
+			{Name: "from_id", Type: "int", Required: true},
+			{Name: "to_id", Type: "int", Required: true},
+			{Name: "amount", Type: "float", Required: true},
+			{Name: "at", Type: "time"},
+			{Name: "memo", Default: "transfer"},
+		},
+	}, nil, PostgresDialect)
+	assert.NoError(t, err)
+
+	args, err := r.args(map[string]any{"from_id": float64(1), "to_id": "2", "amount": 10.5, "at": "2024-01-02"})
+	assert.NoError(t, err)
+	assert.Equal(t, []any{int64(1), int64(2), 10.5, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), "transfer"}, args)
+
+	_, err = r.args(map[string]any{"from_id": 1.5, "amount": []any{1}, "fee": 1})
+	var paramsErr *InvalidParamsError
+	assert.ErrorAs(t, err, &paramsErr)
+	names := make([]string, len(paramsErr.Params))
+	for i, p := range paramsErr.Params {
+		names[i] = p.Name
+	}
+	assert.Equal(t, []string{"from_id", "to_id", "amount", "fee"}, names)
+}
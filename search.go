@@ -0,0 +1,102 @@
+package crudo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 默认的 PostgreSQL 全文搜索配置
+const DefaultSearchLanguage = "simple"
+
+// WithSearch 设置 q 参数搜索的列，未启用全文搜索时对这些列做大小写不敏感的包含匹配
+func WithSearch(fields []string) CrudOption {
+	return func(c *Crud) {
+		c.SearchFields = fields
+	}
+}
+
+// WithFullTextSearch 启用全文搜索
+// PostgreSQL 中 vector 为预先计算的 tsvector 列，为空时按 search_fields 计算 to_tsvector，language 为全文搜索配置；
+// MySQL 中要求 search_fields 上建有 FULLTEXT 索引，vector 与 language 不使用
+func WithFullTextSearch(vector, language string) CrudOption {
+	return func(c *Crud) {
+		c.SearchFullText = true
+		c.SearchVector = vector
+		if language != "" {
+			c.SearchLanguage = language
+		}
+	}
+}
+
+// checkSearchConfig 校验搜索配置中的列
+func (c *Crud) checkSearchConfig() error {
+	for _, field := range c.SearchFields {
+		column, ok := c.queryBuilder.columnCache[field]
+		if !ok {
+			return fmt.Errorf("search field not found in table %s: %s", c.Table, field)
+		}
+		if column.DataType != "string" {
+			return fmt.Errorf("search field %s must be a string column", field)
+		}
+	}
+	if !c.SearchFullText {
+		return nil
+	}
+	if c.SearchVector != "" {
+		if c.Dialect.Name() != PostgresDialect.Name() {
+			return fmt.Errorf("search vector is only supported on postgres")
+		}
+		if _, ok := c.queryBuilder.columnCache[c.SearchVector]; !ok {
+			return fmt.Errorf("search vector not found in table %s: %s", c.Table, c.SearchVector)
+		}
+	} else if len(c.SearchFields) == 0 {
+		return fmt.Errorf("full text search on table %s requires search fields or a search vector", c.Table)
+	}
+	return nil
+}
+
+// searchCondition 构建 q 参数的搜索条件
+func (c *Crud) searchCondition(q string, startIndex int) (string, []any, error) {
+	if len(c.SearchFields) == 0 && c.SearchVector == "" {
+		return "", nil, errors.New("invalid request body: search is not enabled for this table")
+	}
+
+	if c.SearchFullText {
+		match, _ := c.Dialect.FullTextSearch(c.quoteFields(c.SearchFields), c.searchVector(), c.SearchLanguage, c.Dialect.Placeholder(startIndex))
+		return match, []any{q}, nil
+	}
+
+	pattern := "%" + escapeLike(q) + "%"
+	conditions := make([]string, len(c.SearchFields))
+	values := make([]any, len(c.SearchFields))
+	for i, field := range c.SearchFields {
+		conditions[i] = c.Dialect.ILike(c.Dialect.Quote(field), c.Dialect.Placeholder(startIndex+i)) + " ESCAPE '" + likeEscape + "'"
+		values[i] = pattern
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", values, nil
+}
+
+// searchRank 返回按相关度降序排序的排序项，只有启用全文搜索时才能按相关度排序
+func (c *Crud) searchRank(q string, startIndex int) (string, []any, error) {
+	if !c.SearchFullText {
+		return "", nil, errors.New("invalid request body: rank requires full text search")
+	}
+	_, rank := c.Dialect.FullTextSearch(c.quoteFields(c.SearchFields), c.searchVector(), c.SearchLanguage, c.Dialect.Placeholder(startIndex))
+	return rank + " DESC", []any{q}, nil
+}
+
+func (c *Crud) searchVector() string {
+	if c.SearchVector == "" {
+		return ""
+	}
+	return c.Dialect.Quote(c.SearchVector)
+}
+
+func (c *Crud) quoteFields(fields []string) []string {
+	quoted := make([]string, len(fields))
+	for i, field := range fields {
+		quoted[i] = c.Dialect.Quote(field)
+	}
+	return quoted
+}
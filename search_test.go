@@ -0,0 +1,55 @@
+package crudo
+
+import (
+	"testing"
+
+	"github.com/kmlixh/gom/v4/define"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchCondition(t *testing.T) {
+	c := &Crud{
+		Dialect:      PostgresDialect,
+		SearchFields: []string{"name", "description"},
+		queryBuilder: &QueryBuilder{columnCache: map[string]define.ColumnInfo{
+			"name":        {Name: "name", DataType: "string"},
+			"description": {Name: "description", DataType: "string"},
+			"document":    {Name: "document", DataType: "string"},
+		}},
+	}
+	assert.NoError(t, c.checkSearchConfig())
+
+	cond, values, err := c.searchCondition("50%", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, `("name" ILIKE $2 ESCAPE '!' OR "description" ILIKE $3 ESCAPE '!')`, cond)
+	assert.Equal(t, []any{"%50!%%", "%50!%%"}, values)
+	_, _, err = c.searchRank("50%", 4)
+	assert.Error(t, err)
+
+	WithFullTextSearch("document", "english")(c)
+	assert.NoError(t, c.checkSearchConfig())
+	cond, values, err = c.searchCondition("red shoes", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, `"document" @@ plainto_tsquery('english', $1)`, cond)
+	assert.Equal(t, []any{"red shoes"}, values)
+	rank, _, err := c.searchRank("red shoes", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, `ts_rank("document", plainto_tsquery('english', $2)) DESC`, rank)
+
+	c.Dialect = MySQLDialect
+	assert.Error(t, c.checkSearchConfig())
+	c.SearchVector = ""
+	assert.NoError(t, c.checkSearchConfig())
+	cond, _, err = c.searchCondition("red", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "MATCH(`name`, `description`) AGAINST(? IN NATURAL LANGUAGE MODE)", cond)
+
+	_, _, err = (&Crud{Dialect: PostgresDialect}).searchCondition("x", 1)
+	assert.Error(t, err)
+}
+
+func TestPostgresFullTextWithoutVector(t *testing.T) {
+	match, rank := PostgresDialect.FullTextSearch([]string{`"a"`, `"b"`}, "", "", "$1")
+	assert.Equal(t, `to_tsvector('simple', concat_ws(' ', "a", "b")) @@ plainto_tsquery('simple', $1)`, match)
+	assert.Equal(t, `ts_rank(to_tsvector('simple', concat_ws(' ', "a", "b")), plainto_tsquery('simple', $1))`, rank)
+}